@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// accessLog writes one JSON line per request, independent of LOG_LEVEL.
+var accessLog = log.NewWithOptions(os.Stderr, log.Options{
+	Formatter:       log.JSONFormatter,
+	ReportTimestamp: true,
+	TimeFormat:      time.RFC3339Nano,
+})
+
+// ids from a proxy in front are kept, as long as they can not break the log lines
+var requestIdRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+func newRequestId() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestLog is the logger of a request, every line carries its request id.
+func requestLog(r *http.Request) *log.Logger {
+	return log.With("request_id", middleware.GetReqID(r.Context()))
+}
+
+// requestLogEntry lets middleware.Recoverer log a panic with the request id. The access line itself is written by
+// accessLogger.
+type requestLogEntry struct {
+	logger *log.Logger
+}
+
+func (e requestLogEntry) Write(status, bytes int, header http.Header, elapsed time.Duration, extra interface{}) {
+}
+
+func (e requestLogEntry) Panic(v interface{}, stack []byte) {
+	e.logger.Error("panic", "err", v, "stack", string(stack))
+}
+
+// requestIdentifier gives every request an id, stored under middleware.RequestIDKey. It is set on the X-Request-Id
+// response header right away, so error responses written further down can pick it up from there. An id from the
+// client is only kept behind a trusted proxy, otherwise anyone could make their requests look like others in the logs.
+func requestIdentifier(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-Id")
+		if !config.TrustProxyHeaders || !requestIdRe.MatchString(requestId) {
+			requestId = newRequestId()
+		}
+
+		w.Header().Set("X-Request-Id", requestId)
+		ctx := context.WithValue(r.Context(), middleware.RequestIDKey, requestId)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(w, middleware.WithLogEntry(r, requestLogEntry{logger: requestLog(r)}))
+	})
+}
+
+// secretUrlParams are route parameters that must not end up in logs.
+var secretUrlParams = []string{"token"}
+
+// loggedPath is the path of a request with the values of secret route parameters replaced.
+func loggedPath(r *http.Request, routeCtx *chi.Context) string {
+	path := r.URL.Path
+	if routeCtx == nil {
+		return path
+	}
+	for _, param := range secretUrlParams {
+		if value := routeCtx.URLParam(param); value != "" {
+			path = strings.ReplaceAll(path, value, "{"+param+"}")
+		}
+	}
+	return path
+}
+
+// cacheStatus tells how a request relates to HTTP caches. The server only sees cache hits as revalidations answered
+// with 304 Not Modified, like the file server does for images. Responses that caches may store are misses, everything
+// else is not cached at all.
+func cacheStatus(status int, header http.Header) string {
+	if status == http.StatusNotModified {
+		return "hit"
+	}
+
+	cacheControl := header.Get("Cache-Control")
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "private") {
+		return "none"
+	}
+	if cacheControl != "" || header.Get("ETag") != "" || header.Get("Last-Modified") != "" {
+		return "miss"
+	}
+	return "none"
+}
+
+// accessLogger logs every request after it is done, with the route pattern instead of only the path, so requests
+// for the same endpoint can be grouped.
+func accessLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			routeCtx := chi.RouteContext(r.Context())
+			route, lang := "", ""
+			if routeCtx != nil {
+				route = routeCtx.RoutePattern()
+				lang = routeCtx.URLParam("lang")
+			}
+
+			accessLog.Info("request",
+				"request_id", middleware.GetReqID(r.Context()),
+				"method", r.Method,
+				"path", loggedPath(r, routeCtx),
+				"route", route,
+				"lang", lang,
+				"status", status,
+				"latency_ms", float64(time.Since(start).Microseconds())/1000,
+				"bytes", ww.BytesWritten(),
+				"cache", cacheStatus(status, ww.Header()),
+				"remote", r.RemoteAddr,
+			)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// testAccessLogRouter sends the access log and the default log to buffers.
+func testAccessLogRouter(t *testing.T) (http.Handler, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	var access, logs bytes.Buffer
+	previousAccessLog, previousLog := accessLog, log.Default()
+	accessLog = log.NewWithOptions(&access, log.Options{Formatter: log.JSONFormatter})
+	log.SetDefault(log.NewWithOptions(&logs, log.Options{Formatter: log.JSONFormatter}))
+	t.Cleanup(func() {
+		accessLog = previousAccessLog
+		log.SetDefault(previousLog)
+		config.TrustProxyHeaders = false
+	})
+
+	r := chi.NewRouter()
+	r.Use(requestIdentifier, accessLogger, middleware.Recoverer)
+	r.Get("/{lang}/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	r.Post("/update/{token}", func(w http.ResponseWriter, r *http.Request) {
+		requestLog(r).Warn("hook called")
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("broken handler")
+	})
+	return r, &access, &logs
+}
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &line); err != nil {
+		t.Fatalf("Could not decode %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return line
+}
+
+func TestAccessLogLine(t *testing.T) {
+	router, access, _ := testAccessLogRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/en/items", nil))
+
+	line := decodeLogLine(t, access)
+	if line["route"] != "/{lang}/items" || line["lang"] != "en" || line["path"] != "/en/items" {
+		t.Errorf("Unexpected route, lang or path in %v", line)
+	}
+	if line["status"] != float64(http.StatusOK) || line["bytes"] != float64(2) {
+		t.Errorf("Unexpected status or size in %v", line)
+	}
+	if line["request_id"] == "" || line["request_id"] != rec.Header().Get("X-Request-Id") {
+		t.Errorf("Expected the request id %q in %v", rec.Header().Get("X-Request-Id"), line)
+	}
+	if line["cache"] != "none" {
+		t.Errorf("Expected an uncached response in %v", line)
+	}
+}
+
+func TestAccessLogCacheStatus(t *testing.T) {
+	router, access, _ := testAccessLogRouter(t)
+
+	images := chi.NewRouter()
+	images.Use(requestIdentifier, accessLogger)
+	modified := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	images.Get("/img/{file}", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "icon.png", modified, strings.NewReader("png"))
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/en/items", nil))
+	if line := decodeLogLine(t, access); line["cache"] != "none" {
+		t.Error("Expected an uncached listing, got ", line["cache"])
+	}
+
+	images.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/img/icon.png", nil))
+	if line := decodeLogLine(t, access); line["cache"] != "miss" {
+		t.Error("Expected a cacheable image to be a miss, got ", line["cache"])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/img/icon.png", nil)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	images.ServeHTTP(httptest.NewRecorder(), req)
+	if line := decodeLogLine(t, access); line["cache"] != "hit" || line["status"] != float64(http.StatusNotModified) {
+		t.Errorf("Expected a revalidated image to be a hit, got %v", line)
+	}
+}
+
+func TestAccessLogRedactsToken(t *testing.T) {
+	router, access, _ := testAccessLogRouter(t)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/update/hook-secret", nil))
+
+	if strings.Contains(access.String(), "hook-secret") {
+		t.Fatal("Expected the token to be kept out of the access log, got ", access.String())
+	}
+	if line := decodeLogLine(t, access); line["path"] != "/update/{token}" || line["route"] != "/update/{token}" {
+		t.Errorf("Expected the token to be replaced in %v", line)
+	}
+}
+
+func TestRequestIdFromClient(t *testing.T) {
+	router, access, _ := testAccessLogRouter(t)
+
+	send := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/en/items", nil)
+		req.Header.Set("X-Request-Id", "proxy-id-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		access.Reset()
+		return rec.Header().Get("X-Request-Id")
+	}
+
+	if id := send(); id == "proxy-id-1" || id == "" {
+		t.Error("Expected a client id to be replaced without a trusted proxy, got ", id)
+	}
+
+	config.TrustProxyHeaders = true
+	if id := send(); id != "proxy-id-1" {
+		t.Error("Expected the id of a trusted proxy to be kept, got ", id)
+	}
+}
+
+func TestRequestLogCarriesRequestId(t *testing.T) {
+	router, _, logs := testAccessLogRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/update/hook-secret", nil))
+	if line := decodeLogLine(t, logs); line["request_id"] != rec.Header().Get("X-Request-Id") {
+		t.Errorf("Expected the request id %q in %v", rec.Header().Get("X-Request-Id"), line)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Error("Expected the panic to be recovered, got ", rec.Code)
+	}
+	line := decodeLogLine(t, logs)
+	if line["request_id"] != rec.Header().Get("X-Request-Id") || line["err"] != "broken handler" {
+		t.Errorf("Expected the panic to be logged with the request id %q, got %v", rec.Header().Get("X-Request-Id"), line)
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/almanax"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	adminScopeAll     = "*"
+	adminScopeUpdate  = "update"
+	adminScopeAlmanax = "almanax"
+	adminScopeSearch  = "search"
+	adminScopeConfig  = "config"
+	adminScopeRead    = "read"
+
+	// signed requests older or newer than this are rejected so a captured request can not be sent again later
+	adminSignatureMaxSkew = 5 * time.Minute
+
+	adminMaxBodySize = 1 << 20
+)
+
+var adminScopes = []string{adminScopeAll, adminScopeUpdate, adminScopeAlmanax, adminScopeSearch, adminScopeConfig, adminScopeRead}
+
+type adminToken struct {
+	Name   string
+	Secret []byte
+	Scopes []string
+}
+
+func (t *adminToken) allows(scope string) bool {
+	return slices.Contains(t.Scopes, adminScopeAll) || slices.Contains(t.Scopes, scope)
+}
+
+// adminTokens is swapped as a whole when the config is reloaded.
+var adminTokens atomic.Pointer[[]adminToken]
+
+// parseAdminTokens reads ADMIN_TOKENS, a comma separated list of name:scopes:secret where scopes are joined by "+",
+// for example "ci:update:s3cret,ops:*:0ther". The name identifies the token in logs and signed requests.
+func parseAdminTokens(raw string) ([]adminToken, error) {
+	tokens := make([]adminToken, 0)
+	for i, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// never put the entry into the error, it could be the secret
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid admin token at position %d, use name:scopes:secret", i+1)
+		}
+
+		token := adminToken{Name: parts[0], Secret: []byte(parts[2])}
+		for _, scope := range strings.Split(parts[1], "+") {
+			if !slices.Contains(adminScopes, scope) {
+				return nil, fmt.Errorf("admin token %s has unknown scope %q", token.Name, scope)
+			}
+			token.Scopes = append(token.Scopes, scope)
+		}
+
+		for _, other := range tokens {
+			if other.Name == token.Name {
+				return nil, fmt.Errorf("admin token %s is defined twice", token.Name)
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// bearerAdminToken compares the secret with every token, so the time it takes does not tell which one matched.
+func bearerAdminToken(tokens []adminToken, secret string) *adminToken {
+	var match *adminToken
+	for i := range tokens {
+		if subtle.ConstantTimeCompare([]byte(secret), tokens[i].Secret) == 1 {
+			match = &tokens[i]
+		}
+	}
+	return match
+}
+
+func adminSignature(secret []byte, timestamp string, method string, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + uri + "\n"))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var errAdminBody = errors.New("could not read body")
+
+// adminSignatureCache remembers the signatures of accepted requests until their timestamp is too old anyway, so a
+// captured request can not be sent again within the allowed skew.
+type adminSignatureCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time // signature to the time it expires
+}
+
+func newAdminSignatureCache() *adminSignatureCache {
+	return &adminSignatureCache{seen: make(map[string]time.Time)}
+}
+
+// remember reports false if the signature was already used.
+func (c *adminSignatureCache) remember(signature string, expires time.Time, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for seen, seenExpires := range c.seen {
+		if !seenExpires.After(now) {
+			delete(c.seen, seen)
+		}
+	}
+
+	if _, found := c.seen[signature]; found {
+		return false
+	}
+	c.seen[signature] = expires
+	return true
+}
+
+var adminSeenSignatures = newAdminSignatureCache()
+
+// signedAdminToken checks a request signed with X-Admin-Key, X-Admin-Timestamp (unix seconds) and X-Admin-Signature,
+// which is the HMAC-SHA256 of "<timestamp>\n<method>\n<request uri>\n<body>" keyed with the token secret. Every
+// signature is only accepted once.
+func signedAdminToken(tokens []adminToken, seen *adminSignatureCache, r *http.Request, now time.Time) (*adminToken, error) {
+	name := r.Header.Get("X-Admin-Key")
+	timestamp := r.Header.Get("X-Admin-Timestamp")
+	signature := r.Header.Get("X-Admin-Signature")
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X-Admin-Timestamp")
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > adminSignatureMaxSkew || skew < -adminSignatureMaxSkew {
+		return nil, fmt.Errorf("X-Admin-Timestamp is too far from the server time")
+	}
+
+	var token *adminToken
+	for i := range tokens {
+		if tokens[i].Name == name {
+			token = &tokens[i]
+		}
+	}
+	if token == nil {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errAdminBody, err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := adminSignature(token.Secret, timestamp, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	if !seen.remember(expected, time.Unix(unix, 0).Add(adminSignatureMaxSkew), now) {
+		return nil, fmt.Errorf("signature was already used")
+	}
+	return token, nil
+}
+
+// adminAuthenticator accepts "Authorization: Bearer <secret>" or a signed request and puts the token into the context.
+// Without configured tokens, the admin API is disabled.
+func adminAuthenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens := adminTokens.Load()
+		if tokens == nil || len(*tokens) == 0 {
+			e.WriteNotFoundResponse(w, "The admin API is disabled.")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, adminMaxBodySize)
+
+		var token *adminToken
+		if secret, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+			token = bearerAdminToken(*tokens, secret)
+			if token == nil {
+				e.WriteUnauthorizedResponse(w, "Invalid admin token.")
+				return
+			}
+		} else if r.Header.Get("X-Admin-Signature") != "" {
+			var err error
+			token, err = signedAdminToken(*tokens, adminSeenSignatures, r, time.Now())
+			if errors.Is(err, errAdminBody) {
+				e.WriteInvalidJsonResponse(w, err.Error())
+				return
+			}
+			if err != nil {
+				e.WriteUnauthorizedResponse(w, err.Error())
+				return
+			}
+		} else {
+			e.WriteUnauthorizedResponse(w, "Missing admin token or signature.")
+			return
+		}
+
+		log.Info("admin request", "token", token.Name, "method", r.Method, "path", r.URL.Path)
+		ctx := context.WithValue(r.Context(), "adminToken", token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requireAdminScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Context().Value("adminToken").(*adminToken)
+			if !token.allows(scope) {
+				e.WriteForbiddenResponse(w, fmt.Sprintf("Token %s is missing the %s scope.", token.Name, scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// adminJob runs a long operation in the background, at most once at a time.
+type adminJob struct {
+	mu         sync.Mutex
+	running    bool
+	startedAt  time.Time
+	finishedAt time.Time
+	lastError  string
+}
+
+type AdminJobStatus struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+func (j *adminJob) start(name string, run func() error) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running {
+		return false
+	}
+	j.running = true
+	j.startedAt = time.Now()
+
+	go func() {
+		err := run()
+		if err != nil {
+			log.Error("admin job failed", "job", name, "err", err)
+		}
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.running = false
+		j.finishedAt = time.Now()
+		j.lastError = ""
+		if err != nil {
+			j.lastError = err.Error()
+		}
+	}()
+	return true
+}
+
+func (j *adminJob) status() AdminJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := AdminJobStatus{Running: j.running, LastError: j.lastError}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+	return status
+}
+
+var (
+	adminUpdateJob        adminJob
+	adminAlmanaxJob       adminJob
+	adminSearchRebuildJob adminJob
+)
+
+type AdminJobResponse struct {
+	Job    string `json:"job"`
+	Status string `json:"status"`
+}
+
+func writeAdminJobStarted(w http.ResponseWriter, job string, started bool) {
+	if !started {
+		e.WriteConflictResponse(w, fmt.Sprintf("The %s job is already running.", job))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(AdminJobResponse{Job: job, Status: "started"}); err != nil {
+		log.Error("could not encode admin response", "err", err)
+	}
+}
+
+// AdminTriggerUpdate downloads the images of a game version and hands it to the update loop.
+func AdminTriggerUpdate(w http.ResponseWriter, r *http.Request) {
+	var updateMessage UpdateMessage
+	if err := json.NewDecoder(r.Body).Decode(&updateMessage); err != nil {
+		e.WriteInvalidJsonResponse(w, err.Error())
+		return
+	}
+
+	if updateMessage.Version == "" {
+		e.WriteInvalidJsonResponse(w, "version is required.")
+		return
+	}
+
+	writeAdminJobStarted(w, "update", adminUpdateJob.start("update", func() error {
+		err := updateTrigger(updateMessage.Version)
+		if errors.Is(err, errUpdateNotNeeded) {
+			log.Info("skipping update", "version", updateMessage.Version, "reason", err)
+			return nil
+		}
+		return err
+	}))
+}
+
+// AdminGatherAlmanax fetches the almanax of the current game version again.
+func AdminGatherAlmanax(w http.ResponseWriter, r *http.Request) {
+	almDb := r.Context().Value("almanaxRepo").(*database.Repository)
+	writeAdminJobStarted(w, "almanax", adminAlmanaxJob.start("almanax", func() error {
+		return almanax.GatherAlmanaxData(almDb, config.CurrentVersion().Version, false, true)
+	}))
+}
+
+// AdminRebuildSearchIndexes builds a fresh search index generation from the in-memory database and switches to it.
+func AdminRebuildSearchIndexes(w http.ResponseWriter, r *http.Request) {
+	almDb := r.Context().Value("almanaxRepo").(*database.Repository)
+	writeAdminJobStarted(w, "search", adminSearchRebuildJob.start("search", func() error {
+		return RebuildSearchIndexes(&database.Version, almDb, UpdateSearchIndex)
+	}))
+}
+
+type AdminConfigReloadResponse struct {
+	ConfigFile string `json:"config_file,omitempty"`
+	LogLevel   string `json:"log_level"`
+	AdminKeys  int    `json:"admin_tokens"`
+}
+
+// AdminReloadConfig reads the config file and environment again and applies the settings that can change while
+// running.
+func AdminReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if err := reloadConfig(); err != nil {
+		e.WriteServerErrorResponse(w, "Could not reload config: "+err.Error())
+		return
+	}
+
+	res := AdminConfigReloadResponse{
+		ConfigFile: viper.ConfigFileUsed(),
+		LogLevel:   log.GetLevel().String(),
+	}
+	if tokens := adminTokens.Load(); tokens != nil {
+		res.AdminKeys = len(*tokens)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+type AdminGenerationsResponse struct {
+	MemDb          string                    `json:"memdb"`
+	Search         string                    `json:"search"`
+	SearchDegraded bool                      `json:"search_degraded"`
+	GameVersion    utils.GameVersion         `json:"game_version"`
+	Jobs           map[string]AdminJobStatus `json:"jobs"`
+}
+
+// AdminGetGenerations tells which red/blue generation of the in-memory database and search indexes is serving and
+// what the admin jobs are doing.
+func AdminGetGenerations(w http.ResponseWriter, r *http.Request) {
+	res := AdminGenerationsResponse{
+		MemDb:          utils.CurrentRedBlueVersionStr(database.Version.MemDb),
+		Search:         utils.CurrentRedBlueVersionStr(database.Version.Search),
+		SearchDegraded: database.SearchDegraded.Load(),
+		GameVersion:    config.CurrentVersion(),
+		Jobs: map[string]AdminJobStatus{
+			"update":  adminUpdateJob.status(),
+			"almanax": adminAlmanaxJob.status(),
+			"search":  adminSearchRebuildJob.status(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/spf13/viper"
+)
+
+func testAdminRouter(t *testing.T, raw string) http.Handler {
+	t.Helper()
+
+	tokens, err := parseAdminTokens(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminTokens.Store(&tokens)
+	adminSeenSignatures = newAdminSignatureCache()
+	t.Cleanup(func() { adminTokens.Store(nil) })
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	return adminAuthenticator(requireAdminScope(adminScopeUpdate)(ok))
+}
+
+func TestParseAdminTokens(t *testing.T) {
+	tokens, err := parseAdminTokens("ci:update+search:s3cret, ops:*:with:colon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || string(tokens[1].Secret) != "with:colon" || !tokens[1].allows(adminScopeConfig) {
+		t.Errorf("Unexpected tokens %+v", tokens)
+	}
+	if tokens[0].allows(adminScopeConfig) || !tokens[0].allows(adminScopeSearch) {
+		t.Errorf("Unexpected scopes %v", tokens[0].Scopes)
+	}
+
+	for _, raw := range []string{"s3cret", "ci:root:s3cret", "ci:update:", "ci:update:a,ci:read:b"} {
+		if _, err := parseAdminTokens(raw); err == nil {
+			t.Errorf("Expected %q to be invalid", raw)
+		} else if strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("Error leaks the secret: %s", err)
+		}
+	}
+}
+
+func TestAdminBearerAndScopes(t *testing.T) {
+	router := testAdminRouter(t, "ci:update:deploy-secret,viewer:read:view-secret")
+
+	cases := map[string]int{
+		"":                     http.StatusUnauthorized,
+		"Bearer wrong":         http.StatusUnauthorized,
+		"Bearer view-secret":   http.StatusForbidden,
+		"Bearer deploy-secret": http.StatusOK,
+	}
+	for authorization, expected := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/admin/update", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != expected {
+			t.Errorf("%q: expected %d, got %d", authorization, expected, rec.Code)
+		}
+	}
+}
+
+func TestAdminSignedRequest(t *testing.T) {
+	router := testAdminRouter(t, "ci:update:deploy-secret")
+	body := `{"version":"3.0.1"}`
+
+	signed := func(timestamp time.Time, secret string, sentBody string) *http.Request {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/admin/update", strings.NewReader(sentBody))
+		req.Header.Set("X-Admin-Key", "ci")
+		req.Header.Set("X-Admin-Timestamp", ts)
+		req.Header.Set("X-Admin-Signature", adminSignature([]byte(secret), ts, http.MethodPost, "/admin/update", []byte(body)))
+		return req
+	}
+
+	cases := []struct {
+		name     string
+		req      *http.Request
+		expected int
+	}{
+		{"valid", signed(time.Now(), "deploy-secret", body), http.StatusOK},
+		{"wrong secret", signed(time.Now(), "other", body), http.StatusUnauthorized},
+		{"changed body", signed(time.Now(), "deploy-secret", `{"version":"0.0.0"}`), http.StatusUnauthorized},
+		{"old timestamp", signed(time.Now().Add(-10*time.Minute), "deploy-secret", body), http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, c.req)
+		if rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d", c.name, c.expected, rec.Code)
+		}
+	}
+
+	now := time.Now()
+	for i, expected := range []int{http.StatusOK, http.StatusUnauthorized} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, signed(now.Add(time.Minute), "deploy-secret", body))
+		if rec.Code != expected {
+			t.Errorf("Sending a signed request %d times: expected %d, got %d", i+1, expected, rec.Code)
+		}
+	}
+}
+
+func TestAdminSignatureCacheExpires(t *testing.T) {
+	cache := newAdminSignatureCache()
+	now := time.Now()
+
+	if !cache.remember("sha256=a", now.Add(time.Minute), now) {
+		t.Error("Expected a new signature to be accepted")
+	}
+	if cache.remember("sha256=a", now.Add(time.Minute), now.Add(30*time.Second)) {
+		t.Error("Expected a used signature to be rejected")
+	}
+	if !cache.remember("sha256=b", now.Add(3*time.Minute), now.Add(2*time.Minute)) || len(cache.seen) != 1 {
+		t.Errorf("Expected expired signatures to be dropped, got %d", len(cache.seen))
+	}
+}
+
+func TestAdminBodyLimit(t *testing.T) {
+	router := testAdminRouter(t, "ci:update:deploy-secret")
+
+	body := strings.Repeat("a", adminMaxBodySize+1)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/admin/update", strings.NewReader(body))
+	req.Header.Set("X-Admin-Key", "ci")
+	req.Header.Set("X-Admin-Timestamp", ts)
+	req.Header.Set("X-Admin-Signature", adminSignature([]byte("deploy-secret"), ts, http.MethodPost, "/admin/update", []byte(body)))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Error("Expected a too large body to be rejected, got ", rec.Code)
+	}
+}
+
+func TestReloadConfigWhileServing(t *testing.T) {
+	viper.Set("LOG_LEVEL", "info")
+	t.Cleanup(func() {
+		viper.Set("LOG_LEVEL", "")
+		viper.Set("WEBHOOK_TOKEN", "")
+		config.SetRuntimeConfig(config.Runtime{})
+	})
+
+	protected := webhookTokenChecker(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			viper.Set("WEBHOOK_TOKEN", "token-"+strconv.Itoa(i%2))
+			if err := applyRuntimeConfig(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/webhooks/almanax", nil)
+		req.Header.Set("Authorization", "Bearer token-0")
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK && rec.Code != http.StatusUnauthorized && rec.Code != http.StatusNotFound {
+			t.Error("Unexpected status while reloading ", rec.Code)
+		}
+	}
+	wg.Wait()
+
+	if token := config.RuntimeConfig().WebhookToken; token != "token-1" {
+		t.Error("Expected the last reload to apply, got ", token)
+	}
+}
@@ -0,0 +1,178 @@
+package almanax
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+func bonusDetailUrl(lang string, bonusId string) string {
+	return fmt.Sprintf("%s/meta/%s/almanax/bonuses/%s", apiBaseUrl(), lang, bonusId)
+}
+
+func translatedBonusType(bonusType *database.BonusType, lang string) string {
+	switch lang {
+	case "fr":
+		return bonusType.NameFr
+	case "de":
+		return bonusType.NameDe
+	case "es":
+		return bonusType.NameEs
+	case "pt":
+		return bonusType.NamePt
+	}
+	return bonusType.NameEn
+}
+
+func translatedBonusDescription(bonus *database.Bonus, lang string) string {
+	switch lang {
+	case "fr":
+		return bonus.DescriptionFr
+	case "de":
+		return bonus.DescriptionDe
+	case "es":
+		return bonus.DescriptionEs
+	case "pt":
+		return bonus.DescriptionPt
+	}
+	return bonus.DescriptionEn
+}
+
+func translatedTributeName(tribute *database.TributeCount, lang string) string {
+	switch lang {
+	case "fr":
+		return tribute.ItemNameFr
+	case "de":
+		return tribute.ItemNameDe
+	case "es":
+		return tribute.ItemNameEs
+	case "pt":
+		return tribute.ItemNamePt
+	}
+	return tribute.ItemNameEn
+}
+
+// GetBonus describes a bonus type: its description variants, how often it occurs in the stored days, when it comes
+// up next and which tribute items are typically asked for with it.
+func GetBonus(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	bonusId := chi.URLParam(r, "bonusId")
+
+	limit, err := getLimitInBoundary(r.URL.Query().Get("limit"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "Invalid limit value: "+err.Error())
+		return
+	}
+
+	almDb := repository(r)
+
+	bonusType, err := almDb.GetBonusType(bonusId)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.WriteNotFoundResponse(w, "Bonus not found.")
+		return
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get bonus type. "+err.Error())
+		return
+	}
+
+	variants, err := almDb.GetBonusVariants(bonusId)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get bonus descriptions. "+err.Error())
+		return
+	}
+
+	from, to, err := almDb.GetAlmanaxWindow()
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get stored Almanax days. "+err.Error())
+		return
+	}
+
+	monthCounts, err := almDb.GetBonusTypeFrequency(from, to, bonusId)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
+	next, err := almDb.GetNextAlmanaxByNameID(newGameClock().today().Format("2006-01-02"), bonusId, 1)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
+	tributes, err := almDb.GetTopTributesByNameID(bonusId, int(limit))
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get tributes. "+err.Error())
+		return
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	res := AlmanaxBonusDetail{
+		Id:       bonusType.NameID,
+		Name:     translatedBonusType(&bonusType, lang),
+		Variants: make([]AlmanaxBonusVariant, 0, len(variants)),
+		Occurrences: AlmanaxFrequency{
+			From:    from,
+			To:      to,
+			ByMonth: make([]AlmanaxMonthFrequency, 0, len(monthCounts)),
+		},
+		TypicalTributes: make([]AlmanaxBonusTribute, 0, len(tributes)),
+	}
+
+	for _, variant := range variants {
+		res.Variants = append(res.Variants, AlmanaxBonusVariant{
+			Description: renderBonusDescription(translatedBonusDescription(&variant.Bonus, lang)),
+			Count:       variant.Count,
+		})
+	}
+
+	for _, monthCount := range monthCounts {
+		res.Occurrences.Total += monthCount.Count
+		res.Occurrences.ByMonth = append(res.Occurrences.ByMonth, AlmanaxMonthFrequency{
+			Month: monthCount.Month,
+			Count: monthCount.Count,
+		})
+	}
+
+	if len(next) != 0 {
+		response, err := renderAlmanaxResponse(&next[0], lang, nil, itemDb)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not render Almanax response. "+err.Error())
+			return
+		}
+		res.Next = &response
+	}
+
+	for i := range tributes {
+		imageUrls, err := tributeImageUrls(itemDb, tributes[i].ItemCategoryId, tributes[i].ItemAnkamaID)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not get tribute images. "+err.Error())
+			return
+		}
+
+		res.TypicalTributes = append(res.TypicalTributes, AlmanaxBonusTribute{
+			AnkamaId:  tributes[i].ItemAnkamaID,
+			Name:      translatedTributeName(&tributes[i], lang),
+			Subtype:   utils.CategoryIdApiMapping(tributes[i].ItemCategoryId),
+			ImageUrls: imageUrls,
+			Count:     tributes[i].Count,
+		})
+	}
+
+	utils.RequestsTotal.Inc()
+
+	utils.WriteCacheHeader(&w)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
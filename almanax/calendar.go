@@ -0,0 +1,157 @@
+package almanax
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+)
+
+const icalDateFormat = "20060102"
+const icalTimestampFormat = "20060102T150405Z"
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// writeIcalLine writes a content line folded to 75 octets as required by RFC 5545, without splitting UTF-8 sequences.
+func writeIcalLine(sb *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // the leading space of a continuation line counts
+	}
+	sb.WriteString(line)
+	sb.WriteString("\r\n")
+}
+
+// icalUid identifies a day independent of its database row, so subscribed calendars replace the event in place when
+// the day changes.
+func icalUid(date string, lang string) string {
+	return fmt.Sprintf("almanax-%s-%s@%s", date, lang, config.ApiHostName)
+}
+
+func renderIcalEvent(sb *strings.Builder, almanax *AlmanaxResponse, m *database.MappedAlmanax, lang string) error {
+	date, err := time.Parse("2006-01-02", almanax.Date)
+	if err != nil {
+		return err
+	}
+
+	description := []string{
+		almanax.Bonus.Description,
+		fmt.Sprintf("%dx %s", almanax.Tribute.Quantity, almanax.Tribute.Item.Name),
+		fmt.Sprintf("%d Kamas", almanax.RewardKamas),
+	}
+	if almanax.RewardXp != nil {
+		description = append(description, fmt.Sprintf("%d XP", *almanax.RewardXp))
+	}
+
+	writeIcalLine(sb, "BEGIN:VEVENT")
+	writeIcalLine(sb, "UID:"+icalUid(almanax.Date, lang))
+	writeIcalLine(sb, "DTSTAMP:"+m.Almanax.UpdatedAt.UTC().Format(icalTimestampFormat))
+	writeIcalLine(sb, "LAST-MODIFIED:"+m.Almanax.UpdatedAt.UTC().Format(icalTimestampFormat))
+	writeIcalLine(sb, "DTSTART;VALUE=DATE:"+date.Format(icalDateFormat))
+	writeIcalLine(sb, "DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format(icalDateFormat))
+	writeIcalLine(sb, "SUMMARY:"+icalEscaper.Replace(fmt.Sprintf("%s: %dx %s", almanax.Bonus.BonusType.Name, almanax.Tribute.Quantity, almanax.Tribute.Item.Name)))
+	writeIcalLine(sb, "DESCRIPTION:"+icalEscaper.Replace(strings.Join(description, "\n")))
+	writeIcalLine(sb, "URL:"+feedDayLink(lang, almanax))
+	writeIcalLine(sb, "TRANSP:TRANSPARENT")
+	writeIcalLine(sb, "END:VEVENT")
+
+	return nil
+}
+
+// GetAlmanaxCalendar exports a date range as iCalendar with one all-day event per day. It takes the same parameters
+// as GetAlmanaxRange.
+func GetAlmanaxCalendar(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	bonusType := r.URL.Query().Get("filter[bonus_type]")
+
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	fromDate, toDate, err := parseAlmanaxRange(r.URL.Query())
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	timezone := r.URL.Query().Get("timezone")
+	if timezone == "" {
+		timezone = "Europe/Paris"
+	}
+
+	almDb := repository(r)
+
+	if bonusType != "" {
+		found, err := isValidBonusType(almDb, bonusType)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not get bonus types. "+err.Error())
+			return
+		}
+
+		if !found {
+			e.WriteInvalidQueryResponse(w, "Invalid bonus type.")
+			return
+		}
+	}
+
+	fromDateStr := fromDate.Format("2006-01-02")
+	toDateStr := toDate.Format("2006-01-02")
+
+	var mappedAlmanax []database.MappedAlmanax
+	if bonusType != "" {
+		mappedAlmanax, err = almDb.GetAlmanaxByDateRangeAndNameID(fromDateStr, toDateStr, bonusType)
+	} else {
+		mappedAlmanax, err = almDb.GetAlmanaxByDateRange(fromDateStr, toDateStr)
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	var sb strings.Builder
+	writeIcalLine(&sb, "BEGIN:VCALENDAR")
+	writeIcalLine(&sb, "VERSION:2.0")
+	writeIcalLine(&sb, "PRODID:-//dofusdude//doduapi//EN")
+	writeIcalLine(&sb, "CALSCALE:GREGORIAN")
+	writeIcalLine(&sb, "METHOD:PUBLISH")
+	writeIcalLine(&sb, "X-WR-CALNAME:"+feedTitle)
+	writeIcalLine(&sb, "X-WR-TIMEZONE:"+timezone)
+	for i := range mappedAlmanax {
+		response, err := renderAlmanaxResponse(&mappedAlmanax[i], lang, levelInt, itemDb)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not render Almanax response. "+err.Error())
+			return
+		}
+
+		if err = renderIcalEvent(&sb, &response, &mappedAlmanax[i], lang); err != nil {
+			e.WriteServerErrorResponse(w, "Could not render calendar event. "+err.Error())
+			return
+		}
+	}
+	writeIcalLine(&sb, "END:VCALENDAR")
+
+	utils.RequestsTotal.Inc()
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="almanax.ics"`)
+	if _, err = w.Write([]byte(sb.String())); err != nil {
+		return
+	}
+}
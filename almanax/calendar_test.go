@@ -0,0 +1,98 @@
+package almanax
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+)
+
+func TestWriteIcalLineFolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		lines int
+	}{
+		{"short", "SUMMARY:Loot", 1},
+		{"exactly 75 octets", "DESCRIPTION:" + strings.Repeat("a", 63), 1},
+		{"76 octets", "DESCRIPTION:" + strings.Repeat("a", 64), 2},
+		{"long", "DESCRIPTION:" + strings.Repeat("a", 200), 3},
+		{"multibyte at the cut", "DESCRIPTION:" + strings.Repeat("a", 62) + strings.Repeat("é", 40), 3},
+	}
+
+	for _, test := range tests {
+		var sb strings.Builder
+		writeIcalLine(&sb, test.line)
+		folded := sb.String()
+
+		if !strings.HasSuffix(folded, "\r\n") {
+			t.Errorf("%s: expected a CRLF at the end", test.name)
+			continue
+		}
+		lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+		if len(lines) != test.lines {
+			t.Errorf("%s: expected %d lines, got %d", test.name, test.lines, len(lines))
+		}
+		for i, line := range lines {
+			if len(line) > 75 {
+				t.Errorf("%s: line %d has %d octets", test.name, i, len(line))
+			}
+			if i > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("%s: continuation line %d must start with a space", test.name, i)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("%s: line %d splits a UTF-8 sequence", test.name, i)
+			}
+		}
+
+		if unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", ""); unfolded != test.line {
+			t.Errorf("%s: unfolding does not give the line back", test.name)
+		}
+	}
+}
+
+func TestIcalUid(t *testing.T) {
+	host := config.ApiHostName
+	config.ApiHostName = "api.dofusdu.de"
+	t.Cleanup(func() { config.ApiHostName = host })
+
+	if uid := icalUid("2024-05-01", "fr"); uid != "almanax-2024-05-01-fr@api.dofusdu.de" {
+		t.Errorf("Unexpected uid %s", uid)
+	}
+	if icalUid("2024-05-01", "fr") == icalUid("2024-05-01", "en") {
+		t.Error("Expected every language to have its own uid")
+	}
+}
+
+func TestRenderIcalEventEscapes(t *testing.T) {
+	var almanax AlmanaxResponse
+	almanax.Date = "2024-12-31"
+	almanax.Bonus.BonusType.Name = "Loot, more; loot"
+	almanax.Bonus.Description = `A\B`
+	almanax.Tribute.Item.Name = "Wheat"
+	almanax.Tribute.Quantity = 3
+
+	m := &database.MappedAlmanax{}
+	m.Almanax.UpdatedAt = time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	var sb strings.Builder
+	if err := renderIcalEvent(&sb, &almanax, m, "en"); err != nil {
+		t.Fatal(err)
+	}
+	event := sb.String()
+
+	for _, expected := range []string{
+		"DTSTAMP:20240501T103000Z\r\n",
+		"DTSTART;VALUE=DATE:20241231\r\n",
+		"DTEND;VALUE=DATE:20250101\r\n",
+		`SUMMARY:Loot\, more\; loot: 3x Wheat` + "\r\n",
+		`DESCRIPTION:A\\B\n3x Wheat\n0 Kamas` + "\r\n",
+	} {
+		if !strings.Contains(event, expected) {
+			t.Errorf("Expected %q in %q", expected, event)
+		}
+	}
+}
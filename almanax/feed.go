@@ -0,0 +1,312 @@
+package almanax
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Guid        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+	Enclosure   *struct {
+		Url    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length int    `xml:"length,attr"`
+	} `xml:"enclosure,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string      `xml:"xml:lang,attr"`
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+const feedTitle = "Almanax"
+
+type feedDay struct {
+	almanax   AlmanaxResponse
+	published time.Time
+	updated   time.Time
+}
+
+// apiBaseUrl is the public prefix of all routes, like https://api.dofusdu.de/dofus3/v1.
+func apiBaseUrl() string {
+	betaRelease := ""
+	if config.IsBeta {
+		betaRelease = "beta"
+	}
+	return fmt.Sprintf("%s://%s/dofus3%s/v%d", config.ApiScheme, config.ApiHostName, betaRelease, config.MajorVersion)
+}
+
+// isValidBonusType reports whether bonusType is the name_id of a known bonus type.
+func isValidBonusType(almDb *database.Repository, bonusType string) (bool, error) {
+	bonusTypes, err := almDb.GetBonusTypes()
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(bonusTypes, func(b database.BonusType) bool { return b.NameID == bonusType }), nil
+}
+
+// loadFeedDays renders the latest almanax days up to today, newest first.
+func loadFeedDays(w http.ResponseWriter, r *http.Request) ([]feedDay, bool) {
+	lang := r.Context().Value("lang").(string)
+
+	bonusType := r.URL.Query().Get("bonus_type")
+	if bonusType == "" {
+		bonusType = r.URL.Query().Get("filter[bonus_type]")
+	}
+
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return nil, false
+	}
+
+	clock := newGameClock()
+
+	almDb := repository(r)
+
+	if bonusType != "" {
+		valid, err := isValidBonusType(almDb, bonusType)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not get bonus types. "+err.Error())
+			return nil, false
+		}
+		if !valid {
+			e.WriteInvalidQueryResponse(w, "Invalid bonus type.")
+			return nil, false
+		}
+	}
+
+	toDate := clock.today()
+	fromDateStr := toDate.AddDate(0, 0, -config.RuntimeConfig().AlmanaxDefaultLookAhead).Format("2006-01-02")
+	toDateStr := toDate.Format("2006-01-02")
+
+	var mappedAlmanax []database.MappedAlmanax
+	if bonusType != "" {
+		mappedAlmanax, err = almDb.GetAlmanaxByDateRangeAndNameID(fromDateStr, toDateStr, bonusType)
+	} else {
+		mappedAlmanax, err = almDb.GetAlmanaxByDateRange(fromDateStr, toDateStr)
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return nil, false
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	days := make([]feedDay, 0, len(mappedAlmanax))
+	for i := len(mappedAlmanax) - 1; i >= 0; i-- {
+		response, err := renderAlmanaxResponse(&mappedAlmanax[i], lang, levelInt, itemDb)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not render Almanax response. "+err.Error())
+			return nil, false
+		}
+
+		date, err := time.Parse("2006-01-02", response.Date)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Invalid Almanax date. "+err.Error())
+			return nil, false
+		}
+		published := clock.start(date)
+
+		updated := mappedAlmanax[i].Almanax.UpdatedAt
+		if updated.Before(published) {
+			updated = published
+		}
+
+		days = append(days, feedDay{
+			almanax:   response,
+			published: published,
+			updated:   updated,
+		})
+	}
+
+	return days, true
+}
+
+func feedDayTitle(almanax *AlmanaxResponse) string {
+	return fmt.Sprintf("%s: %s", almanax.Date, almanax.Bonus.BonusType.Name)
+}
+
+func feedDayLink(lang string, almanax *AlmanaxResponse) string {
+	return fmt.Sprintf("%s/%s/almanax/%s", apiBaseUrl(), lang, almanax.Date)
+}
+
+// feedDayHtml summarizes a day as small HTML snippet for feed readers.
+func feedDayHtml(almanax *AlmanaxResponse) string {
+	var sb strings.Builder
+	if almanax.Tribute.Item.ImageUrls.Icon != "" {
+		sb.WriteString(fmt.Sprintf(`<p><img src="%s" alt="%s"></p>`, html.EscapeString(almanax.Tribute.Item.ImageUrls.Icon), html.EscapeString(almanax.Tribute.Item.Name)))
+	}
+	sb.WriteString(fmt.Sprintf("<p><b>%s</b>: %s</p>", html.EscapeString(almanax.Bonus.BonusType.Name), html.EscapeString(almanax.Bonus.Description)))
+	sb.WriteString(fmt.Sprintf("<p>%dx %s</p>", almanax.Tribute.Quantity, html.EscapeString(almanax.Tribute.Item.Name)))
+	sb.WriteString(fmt.Sprintf("<p>%d Kamas", almanax.RewardKamas))
+	if almanax.RewardXp != nil {
+		sb.WriteString(fmt.Sprintf(", %d XP", *almanax.RewardXp))
+	}
+	sb.WriteString("</p>")
+	return sb.String()
+}
+
+func feedUpdated(days []feedDay) time.Time {
+	var updated time.Time
+	for _, day := range days {
+		if day.updated.After(updated) {
+			updated = day.updated
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return updated
+}
+
+func writeFeed(w http.ResponseWriter, contentType string, feed interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode XML: "+err.Error())
+		return
+	}
+}
+
+func renderRssFeed(lang string, days []feedDay) rssFeed {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feedTitle,
+			Link:          fmt.Sprintf("%s/%s/almanax", apiBaseUrl(), lang),
+			Description:   feedTitle,
+			Language:      lang,
+			LastBuildDate: feedUpdated(days).Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(days)),
+		},
+	}
+
+	for _, day := range days {
+		item := rssItem{
+			Title:       feedDayTitle(&day.almanax),
+			Link:        feedDayLink(lang, &day.almanax),
+			Guid:        feedDayLink(lang, &day.almanax),
+			PubDate:     day.published.Format(time.RFC1123Z),
+			Description: feedDayHtml(&day.almanax),
+		}
+		if icon := day.almanax.Tribute.Item.ImageUrls.Icon; icon != "" {
+			item.Enclosure = &struct {
+				Url    string `xml:"url,attr"`
+				Type   string `xml:"type,attr"`
+				Length int    `xml:"length,attr"`
+			}{Url: icon, Type: "image/png"}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	return feed
+}
+
+func renderAtomFeed(lang string, days []feedDay) atomFeed {
+	feedUrl := fmt.Sprintf("%s/%s/almanax/feed.atom", apiBaseUrl(), lang)
+	feed := atomFeed{
+		Lang:    lang,
+		Id:      feedUrl,
+		Title:   feedTitle,
+		Updated: feedUpdated(days).Format(time.RFC3339),
+		Link: []atomLink{
+			{Href: feedUrl, Rel: "self"},
+			{Href: fmt.Sprintf("%s/%s/almanax", apiBaseUrl(), lang)},
+		},
+		Entries: make([]atomEntry, 0, len(days)),
+	}
+
+	for _, day := range days {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Id:      feedDayLink(lang, &day.almanax),
+			Title:   feedDayTitle(&day.almanax),
+			Updated: day.updated.Format(time.RFC3339),
+			Link:    atomLink{Href: feedDayLink(lang, &day.almanax)},
+			Content: atomContent{Type: "html", Body: feedDayHtml(&day.almanax)},
+		})
+	}
+
+	return feed
+}
+
+func GetAlmanaxFeedRss(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	days, ok := loadFeedDays(w, r)
+	if !ok {
+		return
+	}
+
+	utils.RequestsTotal.Inc()
+	writeFeed(w, "application/rss+xml; charset=utf-8", renderRssFeed(lang, days))
+}
+
+func GetAlmanaxFeedAtom(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	days, ok := loadFeedDays(w, r)
+	if !ok {
+		return
+	}
+
+	utils.RequestsTotal.Inc()
+	writeFeed(w, "application/atom+xml; charset=utf-8", renderAtomFeed(lang, days))
+}
@@ -0,0 +1,91 @@
+package almanax
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+)
+
+func testFeedDays(t *testing.T) []feedDay {
+	t.Helper()
+
+	scheme, host, major := config.ApiScheme, config.ApiHostName, config.MajorVersion
+	config.ApiScheme, config.ApiHostName, config.MajorVersion = "https", "api.dofusdu.de", 1
+	t.Cleanup(func() { config.ApiScheme, config.ApiHostName, config.MajorVersion = scheme, host, major })
+
+	day := func(date string, bonus string, icon string, published time.Time, updated time.Time) feedDay {
+		var almanax AlmanaxResponse
+		almanax.Date = date
+		almanax.Bonus.BonusType.Name = bonus
+		almanax.Bonus.Description = "More <loot> & fun."
+		almanax.Tribute.Item.Name = "Wheat"
+		almanax.Tribute.Item.ImageUrls.Icon = icon
+		almanax.Tribute.Quantity = 3
+		almanax.RewardKamas = 1000
+		return feedDay{almanax: almanax, published: published, updated: updated}
+	}
+
+	may1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	return []feedDay{
+		day("2024-05-02", "Loot", "https://api.dofusdu.de/img/289.png", may1.AddDate(0, 0, 1), may1.AddDate(0, 0, 1)),
+		day("2024-05-01", "Experience", "", may1, may1.Add(36*time.Hour)), // changed by a later release
+	}
+}
+
+func TestRenderRssFeed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeFeed(rec, "application/rss+xml; charset=utf-8", renderRssFeed("en", testFeedDays(t)))
+
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, xml.Header) || rec.Header().Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+		t.Fatalf("Expected an RSS document, got %s %q", rec.Header().Get("Content-Type"), body)
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.Version != "2.0" || feed.Channel.Language != "en" || feed.Channel.LastBuildDate != "Thu, 02 May 2024 12:00:00 +0000" {
+		t.Errorf("Unexpected channel %+v", feed.Channel)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(feed.Channel.Items))
+	}
+
+	newest := feed.Channel.Items[0]
+	if newest.Title != "2024-05-02: Loot" || newest.Guid != "https://api.dofusdu.de/dofus3/v1/en/almanax/2024-05-02" ||
+		newest.PubDate != "Thu, 02 May 2024 00:00:00 +0000" || newest.Enclosure == nil || newest.Enclosure.Url != "https://api.dofusdu.de/img/289.png" {
+		t.Errorf("Unexpected item %+v", newest)
+	}
+	if feed.Channel.Items[1].Enclosure != nil {
+		t.Error("Expected no enclosure without an icon")
+	}
+	if !strings.Contains(newest.Description, "More &lt;loot&gt; &amp; fun.") || !strings.Contains(newest.Description, "3x Wheat") {
+		t.Errorf("Expected the escaped description and the tribute, got %s", newest.Description)
+	}
+}
+
+func TestRenderAtomFeed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeFeed(rec, "application/atom+xml; charset=utf-8", renderAtomFeed("fr", testFeedDays(t)))
+
+	var feed atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.Id != "https://api.dofusdu.de/dofus3/v1/fr/almanax/feed.atom" || feed.Updated != "2024-05-02T12:00:00Z" || len(feed.Link) != 2 || feed.Link[0].Rel != "self" {
+		t.Errorf("Unexpected feed %+v", feed)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(feed.Entries))
+	}
+	if entry := feed.Entries[1]; entry.Updated != "2024-05-02T12:00:00Z" || entry.Content.Type != "html" || !strings.Contains(entry.Content.Body, "<b>Experience</b>") {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+}
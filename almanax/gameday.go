@@ -0,0 +1,157 @@
+package almanax
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+)
+
+// gameClock tells which almanax day is active. A day starts when the wall clock of the game timezone reaches the
+// reset time, so the day is the same for every client and host.
+type gameClock struct {
+	loc   *time.Location
+	reset time.Duration // since midnight, minute precision
+}
+
+func newGameClock() gameClock {
+	loc := config.AlmanaxLocation
+	if loc == nil {
+		loc, _ = time.LoadLocation("Europe/Paris")
+	}
+	return gameClock{loc: loc, reset: config.AlmanaxResetTime}
+}
+
+// ParseResetTime parses a reset time like 00:00 or 05:30.
+func ParseResetTime(reset string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", reset)
+	if err != nil {
+		return 0, fmt.Errorf("invalid almanax reset time %q, use HH:MM", reset)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// start is the first moment of the day with the given date. Around DST changes the reset time can be skipped or
+// happen twice, so it is the first minute on that date where the wall clock is at or after the reset time.
+func (c gameClock) start(date time.Time) time.Time {
+	y, m, d := date.Date()
+	// DST moves the wall clock by at most an hour or two, so the start is close to midnight plus the reset time
+	t := time.Date(y, m, d, 0, 0, 0, 0, c.loc).Add(c.reset - 2*time.Hour)
+	for limit := t.Add(4 * time.Hour); t.Before(limit); t = t.Add(time.Minute) {
+		local := t.In(c.loc)
+		ly, lm, ld := local.Date()
+		if ly == y && lm == m && ld == d && sinceMidnight(local) >= c.reset {
+			return t.Truncate(time.Minute)
+		}
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, c.loc).Add(c.reset)
+}
+
+// date returns the almanax date that is active at t, as midnight UTC like dates parsed from the URL.
+func (c gameClock) date(t time.Time) time.Time {
+	y, m, d := t.In(c.loc).Date()
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if t.Before(c.start(date)) {
+		return date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
+func (c gameClock) today() time.Time {
+	return c.date(time.Now())
+}
+
+// GameToday is the almanax day that is active now.
+func GameToday() time.Time {
+	return newGameClock().today()
+}
+
+// clientToday is the almanax day that is active now. With a timezone, it is the calendar date of the client instead.
+func clientToday(timezone string) (time.Time, error) {
+	if timezone == "" {
+		return GameToday(), nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid timezone.")
+	}
+	y, m, d := time.Now().In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+func writeGameDay(w http.ResponseWriter, r *http.Request, offset int) {
+	lang := r.Context().Value("lang").(string)
+
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	clock := newGameClock()
+	clientLoc := clock.loc
+	if timezone := r.URL.Query().Get("timezone"); timezone != "" {
+		if clientLoc, err = time.LoadLocation(timezone); err != nil {
+			e.WriteInvalidQueryResponse(w, "Invalid timezone.")
+			return
+		}
+	}
+
+	date := clock.today().AddDate(0, 0, offset)
+	dateStr := date.Format("2006-01-02")
+
+	mappedAlmanax, err := repository(r).GetAlmanaxByDateRange(dateStr, dateStr)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax.")
+		return
+	}
+
+	if len(mappedAlmanax) == 0 {
+		e.WriteNotFoundResponse(w, "No Almanax found.")
+		return
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	response, err := renderAlmanaxResponse(&mappedAlmanax[0], lang, levelInt, itemDb)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not render Almanax response. "+err.Error())
+		return
+	}
+
+	utils.RequestsTotal.Inc()
+	utils.RequestsAlmanaxSingle.Inc()
+
+	utils.WriteCacheHeader(&w)
+	err = json.NewEncoder(w).Encode(AlmanaxGameDayResponse{
+		AlmanaxResponse: response,
+		StartsAt:        clock.start(date).In(clientLoc),
+		EndsAt:          clock.start(date.AddDate(0, 0, 1)).In(clientLoc),
+	})
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+// GetAlmanaxToday returns the almanax that is active right now. The start and end of the day are given in the
+// timezone of the client, the game timezone by default.
+func GetAlmanaxToday(w http.ResponseWriter, r *http.Request) {
+	writeGameDay(w, r, 0)
+}
+
+// GetAlmanaxTomorrow returns the almanax that follows the active one.
+func GetAlmanaxTomorrow(w http.ResponseWriter, r *http.Request) {
+	writeGameDay(w, r, 1)
+}
@@ -0,0 +1,77 @@
+package almanax
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func testGameClock(t *testing.T, reset string) gameClock {
+	t.Helper()
+
+	loc, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resetTime, err := ParseResetTime(reset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gameClock{loc: loc, reset: resetTime}
+}
+
+func TestGameClockDate(t *testing.T) {
+	tests := []struct {
+		reset string
+		at    string // UTC
+		date  string
+	}{
+		{"00:00", "2024-03-30T22:59:59Z", "2024-03-30"}, // 23:59:59 CET
+		{"00:00", "2024-03-30T23:00:00Z", "2024-03-31"}, // midnight CET
+		{"00:00", "2024-10-26T21:59:59Z", "2024-10-26"}, // 23:59:59 CEST
+		{"00:00", "2024-10-26T22:00:00Z", "2024-10-27"}, // midnight CEST
+		// 02:30 does not exist on 2024-03-31, the day starts when the clock jumps to 03:00 CEST
+		{"02:30", "2024-03-31T00:59:59Z", "2024-03-30"},
+		{"02:30", "2024-03-31T01:00:00Z", "2024-03-31"},
+		// 02:30 happens twice on 2024-10-27, the day starts at the first one and stays at the second one
+		{"02:30", "2024-10-27T00:29:59Z", "2024-10-26"},
+		{"02:30", "2024-10-27T00:30:00Z", "2024-10-27"},
+		{"02:30", "2024-10-27T01:30:00Z", "2024-10-27"},
+		{"05:00", "2024-10-27T03:59:59Z", "2024-10-26"}, // 04:59:59 CET
+		{"05:00", "2024-10-27T04:00:00Z", "2024-10-27"},
+	}
+
+	for _, test := range tests {
+		clock := testGameClock(t, test.reset)
+		at, err := time.Parse(time.RFC3339, test.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if date := clock.date(at).Format("2006-01-02"); date != test.date {
+			t.Errorf("reset %s at %s: expected %s, got %s", test.reset, test.at, test.date, date)
+		}
+	}
+}
+
+func TestGameClockDaysAreContinuousOverDst(t *testing.T) {
+	for _, reset := range []string{"00:00", "02:00", "02:30", "03:00", "23:30"} {
+		clock := testGameClock(t, reset)
+		for _, from := range []string{"2024-03-29T00:00:00Z", "2024-10-25T00:00:00Z"} {
+			at, _ := time.Parse(time.RFC3339, from)
+			previous := clock.date(at)
+			for end := at.AddDate(0, 0, 4); at.Before(end); at = at.Add(5 * time.Minute) {
+				date := clock.date(at)
+				if !date.Equal(previous) && !date.Equal(previous.AddDate(0, 0, 1)) {
+					t.Fatalf("reset %s: day jumped from %s to %s at %s", reset, previous.Format("2006-01-02"), date.Format("2006-01-02"), at)
+				}
+				previous = date
+
+				start, end := clock.start(date), clock.start(date.AddDate(0, 0, 1))
+				if at.Before(start) || !at.Before(end) {
+					t.Fatalf("reset %s: %s is outside of its day %s to %s", reset, at, start, end)
+				}
+			}
+		}
+	}
+}
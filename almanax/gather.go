@@ -12,6 +12,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/dofusdude/doduapi/config"
 	"github.com/dofusdude/doduapi/database"
+	"github.com/dofusdude/doduapi/utils"
 	"github.com/dofusdude/dodumap"
 	mapping "github.com/dofusdude/dodumap"
 	"github.com/google/go-github/v67/github"
@@ -24,11 +25,12 @@ var (
 	MappedAlmanaxFileName = "MAPPED_ALMANAX.json"
 )
 
+// dateRange lists all dates from from to to, both included.
 func dateRange(from, to time.Time) ([]string, error) {
 	layout := "2006-01-02"
 	var dates []string
 
-	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
 		dates = append(dates, d.Format(layout))
 	}
 
@@ -36,7 +38,7 @@ func dateRange(from, to time.Time) ([]string, error) {
 }
 
 func UpdateAlmanaxBonusIndex(init bool, db *database.Repository) int {
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
 	added := 0
@@ -179,22 +181,21 @@ func UpdateAlmanaxBonusIndex(init bool, db *database.Repository) int {
 	return added
 }
 
-func GatherAlmanaxData(initial bool, headless bool) error {
-	db := database.NewDatabaseRepository(context.Background(), config.DbDir)
-	defer db.Deinit()
-
-	almanaxData, err := loadAlmanaxData(config.DofusVersion)
+// IngestAlmanax stores the next year of the almanax from the given data release. With dryRun, nothing is written and
+// the summary lists the days that would change.
+func IngestAlmanax(db *database.Repository, version string, dryRun bool) (database.IngestSummary, error) {
+	almanaxData, err := loadAlmanaxData(version)
 	if err != nil {
-		return fmt.Errorf("could not load almanax data: %w", err)
+		return database.IngestSummary{}, fmt.Errorf("could not load almanax data: %w", err)
 	}
 
 	yearLookup := make(map[string]dodumap.MappedMultilangNPCAlmanaxUnity)
 
-	today := time.Now()
+	today := newGameClock().today()
 	yearFromNow := today.AddDate(1, 0, 0)
 	dates, err := dateRange(today, yearFromNow)
 	if err != nil {
-		return fmt.Errorf("could not generate date range: %w", err)
+		return database.IngestSummary{}, fmt.Errorf("could not generate date range: %w", err)
 	}
 
 	datesNotFound := 0
@@ -217,16 +218,27 @@ func GatherAlmanaxData(initial bool, headless bool) error {
 	}
 
 	if datesNotFound > len(dates)/2 {
-		return fmt.Errorf("could not find enough almanax data for the next year")
+		return database.IngestSummary{}, fmt.Errorf("could not find enough almanax data for the next year")
 	}
 
-	err = db.UpdateFuture(yearLookup)
+	return db.UpdateFuture(yearLookup, version, dryRun)
+}
+
+// GatherAlmanaxData stores the next year of the almanax from the data release of gameVersion, the one being served or
+// about to be.
+func GatherAlmanaxData(db *database.Repository, gameVersion string, initial bool, headless bool) error {
+	summary, err := IngestAlmanax(db, gameVersion, false)
 	if err != nil {
 		return err
 	}
 
 	if headless {
-		log.Info("Next Almanax year updated successfully")
+		log.Info("Next Almanax year updated successfully", "inserted", summary.Inserted, "updated", summary.Updated, "unchanged", summary.Unchanged)
+	}
+
+	if database.SearchDegraded.Load() {
+		log.Warn("search engine unavailable, Almanax bonus index will be rebuilt when it is back")
+		return nil
 	}
 
 	added := UpdateAlmanaxBonusIndex(initial, db)
@@ -1,13 +1,14 @@
 package almanax
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dofusdude/doduapi/config"
@@ -19,31 +20,23 @@ import (
 	"github.com/meilisearch/meilisearch-go"
 )
 
+// repository returns the almanax database the router injected into the request.
+func repository(r *http.Request) *database.Repository {
+	return r.Context().Value("almanaxRepo").(*database.Repository)
+}
+
 var bonusDescriptionTemplateRe = regexp.MustCompile(`{{([^,]+),([0-9]+)::([^{]+)}}`)
 
 func GetAlmanaxSingle(w http.ResponseWriter, r *http.Request) {
 	lang := r.Context().Value("lang").(string)
 	date := r.Context().Value("date").(time.Time)
-	level := r.URL.Query().Get("level")
-
-	var levelInt *int
-	if level != "" {
-		levelParse, err := strconv.Atoi(level)
-		if err != nil {
-			e.WriteInvalidQueryResponse(w, "Invalid level value.")
-			return
-		}
-
-		if levelParse < 1 || levelParse > 200 {
-			e.WriteInvalidQueryResponse(w, "Level value out of bounds.")
-			return
-		}
-
-		levelInt = &levelParse
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
 	}
 
-	almDb := database.NewDatabaseRepository(context.Background(), config.DbDir)
-	defer almDb.Deinit()
+	almDb := repository(r)
 
 	dateStr := date.Format("2006-01-02")
 	mappedAlmanax, err := almDb.GetAlmanaxByDateRange(dateStr, dateStr)
@@ -82,9 +75,27 @@ func GetAlmanaxSingle(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// parseLevelParam parses the optional character level used for experience rewards.
+func parseLevelParam(level string) (*int, error) {
+	if level == "" {
+		return nil, nil
+	}
+
+	levelParse, err := strconv.Atoi(level)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid level value.")
+	}
+
+	if levelParse < 1 || levelParse > 200 {
+		return nil, fmt.Errorf("Level value out of bounds.")
+	}
+
+	return &levelParse, nil
+}
+
 func experienceReward(playerLevel, optimalLevel int, xpRatio, duration float64) int {
 	if playerLevel == -1 {
-		playerLevel = 200
+		playerLevel = maxCharacterLevel
 	}
 
 	if playerLevel > optimalLevel {
@@ -99,6 +110,32 @@ func experienceReward(playerLevel, optimalLevel int, xpRatio, duration float64)
 	return int(math.Floor(float64(playerLevel) * math.Pow(100.0+2.0*float64(playerLevel), 2.0) / 20.0 * duration * xpRatio))
 }
 
+// tributeImageUrls looks up the images of a tribute item. Days from the history can have tributes that are no longer
+// in the game, they just have no images.
+func tributeImageUrls(txn *memdb.Txn, categoryId int, ankamaId int64) (ApiImageUrls, error) {
+	categoryDbType := utils.CategoryIdMapping(categoryId)
+
+	raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), categoryDbType), "id", ankamaId)
+	if err != nil || raw == nil {
+		return ApiImageUrls{}, err
+	}
+
+	item := raw.(*mapping.MappedMultilangItemUnity)
+	return RenderImageUrls(utils.ImageUrls(item.IconId, "item", config.ItemImgResolutions, config.ApiScheme, config.MajorVersion, config.ApiHostName, config.IsBeta)), nil
+}
+
+// renderBonusDescription replaces templated links inside the bonus description; TODO replace this later with a meta
+// link to the linked item or monster
+func renderBonusDescription(description string) string {
+	return bonusDescriptionTemplateRe.ReplaceAllStringFunc(description, func(match string) string {
+		group := bonusDescriptionTemplateRe.FindStringSubmatch(match)
+		if len(group) < 4 {
+			return match // return original if for some reason we do not have enough captures
+		}
+		return group[3] // only return the last capture group, which is the localized name
+	})
+}
+
 func renderAlmanaxResponse(m *database.MappedAlmanax, lang string, level *int, txn *memdb.Txn) (AlmanaxResponse, error) {
 	var response AlmanaxResponse
 	response.Date = m.Almanax.Date
@@ -108,19 +145,11 @@ func renderAlmanaxResponse(m *database.MappedAlmanax, lang string, level *int, t
 	response.Tribute.Item.AnkamaId = m.Tribute.ItemAnkamaID
 	response.Tribute.Item.Subtype = utils.CategoryIdApiMapping(m.Tribute.ItemCategoryId)
 
-	categoryDbType := utils.CategoryIdMapping(m.Tribute.ItemCategoryId)
-
-	raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), categoryDbType), "id", response.Tribute.Item.AnkamaId)
+	imageUrls, err := tributeImageUrls(txn, m.Tribute.ItemCategoryId, m.Tribute.ItemAnkamaID)
 	if err != nil {
 		return response, err
 	}
-
-	if raw == nil {
-		return response, fmt.Errorf("Item %d not found in %s database.", response.Tribute.Item.AnkamaId, categoryDbType)
-	}
-
-	item := raw.(*mapping.MappedMultilangItemUnity)
-	response.Tribute.Item.ImageUrls = RenderImageUrls(utils.ImageUrls(item.IconId, "item", config.ItemImgResolutions, config.ApiScheme, config.MajorVersion, config.ApiHostName, config.IsBeta))
+	response.Tribute.Item.ImageUrls = imageUrls
 
 	switch lang {
 	case "en":
@@ -145,14 +174,7 @@ func renderAlmanaxResponse(m *database.MappedAlmanax, lang string, level *int, t
 		response.Tribute.Item.Name = m.Tribute.ItemNamePt
 	}
 
-	// replace templated links inside the bonus description; TODO replace this later with a meta link to the linked item or monster
-	response.Bonus.Description = bonusDescriptionTemplateRe.ReplaceAllStringFunc(response.Bonus.Description, func(match string) string {
-		group := bonusDescriptionTemplateRe.FindStringSubmatch(match)
-		if len(group) < 4 {
-			return match // return original if for some reason we do not have enough captures
-		}
-		return group[3] // only return the last capture group, which is the localized name
-	})
+	response.Bonus.Description = renderBonusDescription(response.Bonus.Description)
 
 	if level != nil {
 		response.RewardXp = new(int)
@@ -162,56 +184,32 @@ func renderAlmanaxResponse(m *database.MappedAlmanax, lang string, level *int, t
 	return response, nil
 }
 
-func GetAlmanaxRange(w http.ResponseWriter, r *http.Request) {
-	lang := r.Context().Value("lang").(string)
-	from := r.URL.Query().Get("range[from]")
-	to := r.URL.Query().Get("range[to]")
-	size := r.URL.Query().Get("range[size]")
+// parseAlmanaxRange resolves range[from], range[to] and range[size] into a date range. Missing bounds default to today
+// in the requested timezone and the default lookahead.
+func parseAlmanaxRange(query url.Values) (time.Time, time.Time, error) {
+	from := query.Get("range[from]")
+	to := query.Get("range[to]")
+	size := query.Get("range[size]")
 	var sizeNum int
-	bonusType := r.URL.Query().Get("filter[bonus_type]")
-	timezone := r.URL.Query().Get("timezone")
-
-	level := r.URL.Query().Get("level")
-	var levelInt *int
-	if level != "" {
-		levelParse, err := strconv.Atoi(level)
-		if err != nil {
-			e.WriteInvalidQueryResponse(w, "Invalid level value.")
-			return
-		}
-
-		if levelParse < 1 || levelParse > 200 {
-			e.WriteInvalidQueryResponse(w, "Level value out of bounds.")
-			return
-		}
-
-		levelInt = &levelParse
-	}
+	timezone := query.Get("timezone")
 
 	var err error
-
 	if size == "" {
 		sizeNum = -1
 	} else {
 		sizeNum, err = strconv.Atoi(size)
 		if err != nil {
-			e.WriteInvalidQueryResponse(w, "Invalid size value.")
-			return
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid size value.")
 		}
 	}
 
-	if timezone == "" {
-		timezone = "Europe/Paris"
-	}
-
 	givenFromDate := from != ""
 	var fromDate time.Time
 	var fromDateParsed time.Time
 	if givenFromDate {
 		fromDateParsed, err = time.Parse("2006-01-02", from)
 		if err != nil {
-			e.WriteInvalidQueryResponse(w, "Invalid from-date format.")
-			return
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid from-date format.")
 		}
 	}
 
@@ -221,32 +219,25 @@ func GetAlmanaxRange(w http.ResponseWriter, r *http.Request) {
 	if givenToDate {
 		toDateParsed, err = time.Parse("2006-01-02", to)
 		if err != nil {
-			e.WriteInvalidQueryResponse(w, "Invalid to-date format.")
-			return
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid to-date format.")
 		}
 	}
 
-	loc, err := time.LoadLocation(timezone)
+	today, err := clientToday(timezone)
 	if err != nil {
-		e.WriteInvalidQueryResponse(w, "Invalid timezone.")
-		return
+		return time.Time{}, time.Time{}, err
 	}
-	fromDate = time.Now().In(loc)
-	toDate = fromDate.AddDate(0, 0, config.AlmanaxDefaultLookAhead)
+	settings := config.RuntimeConfig()
+	fromDate = today
+	toDate = fromDate.AddDate(0, 0, settings.AlmanaxDefaultLookAhead)
 
 	givenRangeSize := size != "" && sizeNum > 0
 	if givenRangeSize && givenFromDate && givenToDate {
-		e.WriteInvalidQueryResponse(w, "Cannot use range[size] with range[from] and range[to].")
-		return
+		return time.Time{}, time.Time{}, fmt.Errorf("Cannot use range[size] with range[from] and range[to].")
 	}
 
 	if givenRangeSize && !givenFromDate && !givenToDate {
-		loc, err := time.LoadLocation(timezone)
-		if err != nil {
-			e.WriteInvalidQueryResponse(w, "Invalid timezone.")
-			return
-		}
-		fromDate = time.Now().In(loc)
+		fromDate = today
 		toDate = fromDate.AddDate(0, 0, sizeNum)
 	} else {
 		if givenFromDate && givenToDate {
@@ -256,7 +247,7 @@ func GetAlmanaxRange(w http.ResponseWriter, r *http.Request) {
 
 		if givenFromDate && !givenToDate {
 			fromDate = fromDateParsed
-			toDate = fromDate.AddDate(0, 0, config.AlmanaxDefaultLookAhead)
+			toDate = fromDate.AddDate(0, 0, settings.AlmanaxDefaultLookAhead)
 		}
 
 		if !givenFromDate && givenToDate {
@@ -274,33 +265,41 @@ func GetAlmanaxRange(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if fromDate.After(toDate) {
-		e.WriteInvalidQueryResponse(w, "From-date is after to-date.")
+		return time.Time{}, time.Time{}, fmt.Errorf("From-date is after to-date.")
+	}
+
+	if toDate.Sub(fromDate).Hours() > float64(settings.AlmanaxMaxLookAhead)*24 {
+		return time.Time{}, time.Time{}, fmt.Errorf("Date range is too large.")
+	}
+
+	return fromDate, toDate, nil
+}
+
+func GetAlmanaxRange(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	bonusType := r.URL.Query().Get("filter[bonus_type]")
+
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
 		return
 	}
 
-	if toDate.Sub(fromDate).Hours() > float64(config.AlmanaxMaxLookAhead)*24 {
-		e.WriteInvalidQueryResponse(w, "Date range is too large.")
+	fromDate, toDate, err := parseAlmanaxRange(r.URL.Query())
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
 		return
 	}
 
-	almDb := database.NewDatabaseRepository(context.Background(), config.DbDir)
-	defer almDb.Deinit()
+	almDb := repository(r)
 
 	if bonusType != "" {
-		bonusTypes, err := almDb.GetBonusTypes()
+		found, err := isValidBonusType(almDb, bonusType)
 		if err != nil {
 			e.WriteServerErrorResponse(w, "Could not get bonus types. "+err.Error())
 			return
 		}
 
-		found := false
-		for _, b := range bonusTypes {
-			if b.NameID == bonusType {
-				found = true
-				break
-			}
-		}
-
 		if !found {
 			e.WriteInvalidQueryResponse(w, "Invalid bonus type.")
 			return
@@ -359,6 +358,7 @@ func bonusListingsToBonusIdTranslated(bonuses []database.BonusType, lang string)
 	for _, bonus := range bonuses {
 		var bonusTranslated AlmanaxBonusListing
 		bonusTranslated.Id = bonus.NameID
+		bonusTranslated.Url = bonusDetailUrl(lang, bonus.NameID)
 		switch lang {
 		case "en":
 			bonusTranslated.Name = bonus.NameEn
@@ -379,7 +379,7 @@ func bonusListingsToBonusIdTranslated(bonuses []database.BonusType, lang string)
 
 func ListBonuses(w http.ResponseWriter, r *http.Request) {
 	lang := r.Context().Value("lang").(string)
-	db := database.NewDatabaseRepository(context.Background(), config.DbDir)
+	db := repository(r)
 
 	bonuses, err := db.GetBonusTypes()
 	if err != nil {
@@ -411,6 +411,9 @@ func getLimitInBoundary(limitStr string) (int64, error) {
 	if limit, err = strconv.Atoi(limitStr); err != nil {
 		return 0, fmt.Errorf("invalid limit value")
 	}
+	if limit < 0 {
+		return 0, fmt.Errorf("limit value must not be negative")
+	}
 	if limit > 100 {
 		return 0, fmt.Errorf("limit value is too high")
 	}
@@ -423,7 +426,7 @@ func SetJsonHeader(w *http.ResponseWriter) {
 }
 
 func SearchBonuses(w http.ResponseWriter, r *http.Request) {
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
 	query := r.URL.Query().Get("query")
@@ -447,30 +450,46 @@ func SearchBonuses(w http.ResponseWriter, r *http.Request) {
 		Limit: searchLimit,
 	}
 
-	var searchResp *meilisearch.SearchResponse
-	if searchResp, err = index.Search(query, request); err != nil {
-		e.WriteServerErrorResponse(w, "Could not search: "+err.Error())
-		return
+	var results []AlmanaxBonusListing
+	degraded := database.SearchDegraded.Load()
+	if !degraded {
+		var searchResp *meilisearch.SearchResponse
+		if searchResp, err = index.Search(query, request); err != nil {
+			if !database.SearchEngineUnreachable(err) {
+				e.WriteServerErrorResponse(w, "Could not search: "+err.Error())
+				return
+			}
+			database.SearchDegraded.Store(true)
+			degraded = true
+		} else {
+			for _, hit := range searchResp.Hits {
+				almBonusJson := hit.(map[string]interface{})
+				almBonus := AlmanaxBonusListing{
+					Id:   almBonusJson["slug"].(string),
+					Name: almBonusJson["name"].(string),
+				}
+				almBonus.Url = bonusDetailUrl(lang, almBonus.Id)
+				results = append(results, almBonus)
+			}
+		}
 	}
 
-	//requestsTotal.Inc()
-	//requestsSearchTotal.Inc()
+	if degraded {
+		if results, err = searchBonusesInMemory(repository(r), query, lang, searchLimit); err != nil {
+			e.WriteServerErrorResponse(w, "Could not search: "+err.Error())
+			return
+		}
+		w.Header().Set(utils.DegradedSearchHeader, "true")
+	}
 
-	if searchResp.EstimatedTotalHits == 0 {
+	utils.RequestsTotal.Inc()
+	utils.RequestsAlmanaxBonusSearch.Inc()
+
+	if len(results) == 0 {
 		e.WriteNotFoundResponse(w, "No results found.")
 		return
 	}
 
-	var results []AlmanaxBonusListing
-	for _, hit := range searchResp.Hits {
-		almBonusJson := hit.(map[string]interface{})
-		almBonus := AlmanaxBonusListing{
-			Id:   almBonusJson["slug"].(string),
-			Name: almBonusJson["name"].(string),
-		}
-		results = append(results, almBonus)
-	}
-
 	utils.WriteCacheHeader(&w)
 	err = json.NewEncoder(w).Encode(results)
 	if err != nil {
@@ -478,3 +497,30 @@ func SearchBonuses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// searchBonusesInMemory matches bonus type names from the almanax database while the search engine is unavailable.
+// Names starting with the query come before names that only contain it.
+func searchBonusesInMemory(db *database.Repository, query string, lang string, limit int64) ([]AlmanaxBonusListing, error) {
+	bonusTypes, err := db.GetBonusTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	var prefixMatches, substringMatches []AlmanaxBonusListing
+	for _, bonus := range bonusListingsToBonusIdTranslated(bonusTypes, lang) {
+		name := strings.ToLower(bonus.Name)
+		if strings.HasPrefix(name, query) {
+			prefixMatches = append(prefixMatches, bonus)
+		} else if strings.Contains(name, query) {
+			substringMatches = append(substringMatches, bonus)
+		}
+	}
+
+	results := append(prefixMatches, substringMatches...)
+	if limit > 0 && len(results) > int(limit) {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
@@ -0,0 +1,167 @@
+package almanax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	mapping "github.com/dofusdude/dodumap"
+	"github.com/google/go-github/v67/github"
+)
+
+type dataRelease struct {
+	version    string // named like the live ingest, so its days are recognized as the same release
+	tag        string // where the assets are published
+	releasedAt time.Time
+}
+
+// listDataReleases returns the published releases of the data repository, oldest first. Without versions, all of them
+// are listed.
+func listDataReleases(versions []string) ([]dataRelease, error) {
+	client := github.NewClient(nil)
+
+	var releases []*github.RepositoryRelease
+	if len(versions) == 0 {
+		opts := &github.ListOptions{PerPage: 100}
+		for {
+			page, res, err := client.Repositories.ListReleases(context.Background(), DataRepoOwner, DataRepoName, opts)
+			if err != nil {
+				return nil, fmt.Errorf("could not list releases: %w", err)
+			}
+			releases = append(releases, page...)
+			if res.NextPage == 0 {
+				break
+			}
+			opts.Page = res.NextPage
+		}
+	} else {
+		for _, version := range versions {
+			release, _, err := client.Repositories.GetReleaseByTag(context.Background(), DataRepoOwner, DataRepoName, version)
+			if err != nil {
+				return nil, fmt.Errorf("could not get release %s: %w", version, err)
+			}
+			releases = append(releases, release)
+		}
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].GetPublishedAt().Before(releases[j].GetPublishedAt().Time)
+	})
+
+	dataReleases := make([]dataRelease, 0, len(releases))
+	for _, release := range releases {
+		if release.GetDraft() {
+			continue
+		}
+		dataReleases = append(dataReleases, dataRelease{
+			version:    utils.ReleaseVersion(release.GetName(), release.GetTagName()),
+			tag:        release.GetTagName(),
+			releasedAt: release.GetPublishedAt().Time,
+		})
+	}
+
+	return dataReleases, nil
+}
+
+// BackfillAlmanaxHistory stores the past days of older data releases as history, dated by the publishing time of their
+// release. A day is only current when no newer release is stored for it, so the versions can be given in any order.
+// Without versions, all releases of the data repository are used.
+func BackfillAlmanaxHistory(db *database.Repository, versions []string) error {
+	releases, err := listDataReleases(versions)
+	if err != nil {
+		return err
+	}
+
+	today := newGameClock().today().Format("2006-01-02")
+
+	for _, release := range releases {
+		almanaxData, err := loadAlmanaxData(release.tag)
+		if err != nil {
+			log.Warn("skipping release without almanax data", "version", release.version, "err", err)
+			continue
+		}
+
+		summary, err := backfillRelease(db, release, almanaxData, today)
+		if err != nil {
+			return fmt.Errorf("could not backfill %s: %w", release.version, err)
+		}
+
+		log.Info("backfilled almanax", "version", release.version, "inserted", summary.Inserted, "updated", summary.Updated, "unchanged", summary.Unchanged)
+	}
+
+	return nil
+}
+
+// backfillRelease stores the days of a release that are before today.
+func backfillRelease(db *database.Repository, release dataRelease, almanaxData []mapping.MappedMultilangNPCAlmanaxUnity, today string) (database.IngestSummary, error) {
+	pastLookup := make(map[string]mapping.MappedMultilangNPCAlmanaxUnity)
+	for _, almanax := range almanaxData {
+		for _, day := range almanax.Days {
+			if day < today {
+				pastLookup[day] = almanax
+			}
+		}
+	}
+
+	return db.BackfillAlmanax(pastLookup, release.version, release.releasedAt)
+}
+
+// GetAlmanaxHistory lists all assignments a day had over the game versions, oldest first. The last entry without
+// replaced_at is the current one.
+func GetAlmanaxHistory(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	date := r.Context().Value("date").(time.Time)
+
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	almDb := repository(r)
+
+	mappedAlmanax, err := almDb.GetAlmanaxHistory(date.Format("2006-01-02"))
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax history. "+err.Error())
+		return
+	}
+
+	if len(mappedAlmanax) == 0 {
+		e.WriteNotFoundResponse(w, "No Almanax found for this date.")
+		return
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	history := make([]AlmanaxHistoryEntry, 0, len(mappedAlmanax))
+	for i := range mappedAlmanax {
+		response, err := renderAlmanaxResponse(&mappedAlmanax[i], lang, levelInt, itemDb)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not render Almanax response. "+err.Error())
+			return
+		}
+
+		history = append(history, AlmanaxHistoryEntry{
+			GameVersion: mappedAlmanax[i].Almanax.GameVersion,
+			ValidFrom:   mappedAlmanax[i].Almanax.CreatedAt,
+			ReplacedAt:  mappedAlmanax[i].Almanax.DeletedAt,
+			Almanax:     response,
+		})
+	}
+
+	utils.RequestsTotal.Inc()
+
+	utils.WriteCacheHeader(&w)
+	if err = json.NewEncoder(w).Encode(history); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
@@ -0,0 +1,119 @@
+package almanax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/database"
+	"github.com/dofusdude/dodumap"
+)
+
+func testRelease(version string, releasedAt string, days map[string]string) (dataRelease, []dodumap.MappedMultilangNPCAlmanaxUnity) {
+	released, _ := time.Parse(time.DateOnly, releasedAt)
+
+	almanaxData := make([]dodumap.MappedMultilangNPCAlmanaxUnity, 0, len(days))
+	for day, bonusType := range days {
+		almanax := testAlmanax(bonusType)
+		almanax.Days = []string{day}
+		almanaxData = append(almanaxData, *almanax)
+	}
+
+	return dataRelease{version: version, releasedAt: released}, almanaxData
+}
+
+func almanaxHistory(t *testing.T, repo *database.Repository, date string) []database.MappedAlmanax {
+	t.Helper()
+
+	history, err := repo.GetAlmanaxHistory(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func TestBackfillKeepsNewerReleasesCurrent(t *testing.T) {
+	repo := testRepository(t)
+
+	live := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-05-01": *testAlmanax("Harvest"),
+		"2024-05-02": *testAlmanax("Harvest"),
+	}
+	if _, err := repo.UpdateFuture(live, "3.1", false); err != nil {
+		t.Fatal(err)
+	}
+
+	release, almanaxData := testRelease("3.0", "2024-01-01", map[string]string{
+		"2024-05-01": "Loot",
+		"2024-05-02": "Harvest",
+		"2024-07-01": "Loot",
+	})
+	summary, err := backfillRelease(repo, release, almanaxData, "2024-06-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Inserted != 1 || summary.Updated != 1 || summary.Unchanged != 0 {
+		t.Errorf("Expected 1 inserted and 1 updated day, got %+v", summary)
+	}
+
+	history := almanaxHistory(t, repo, "2024-05-01")
+	if len(history) != 2 {
+		t.Fatal("Expected 2 assignments, got ", len(history))
+	}
+	old, current := history[0].Almanax, history[1].Almanax
+	if old.GameVersion != "3.0" || history[0].BonusType.NameEn != "Loot" {
+		t.Errorf("Expected the backfilled release first, got %s %s", old.GameVersion, history[0].BonusType.NameEn)
+	}
+	if !old.CreatedAt.Equal(release.releasedAt) {
+		t.Error("Expected the backfilled day to be valid from its release, got ", old.CreatedAt)
+	}
+	if old.DeletedAt == nil || !old.DeletedAt.Equal(current.CreatedAt) {
+		t.Error("Expected the backfilled day to be replaced by the live one, got ", old.DeletedAt)
+	}
+	if current.GameVersion != "3.1" || current.DeletedAt != nil {
+		t.Errorf("Expected the live release to stay current, got %s replaced at %v", current.GameVersion, current.DeletedAt)
+	}
+
+	unchangedDay := almanaxHistory(t, repo, "2024-05-02")
+	if len(unchangedDay) != 1 || !unchangedDay[0].Almanax.CreatedAt.Equal(release.releasedAt) {
+		t.Error("Expected the unchanged day to be valid since the older release, got ", unchangedDay)
+	}
+
+	if future := almanaxHistory(t, repo, "2024-07-01"); len(future) != 0 {
+		t.Error("Expected no days after today to be backfilled, got ", len(future))
+	}
+
+	summary, err = backfillRelease(repo, release, almanaxData, "2024-06-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Unchanged != 2 || summary.Inserted != 0 || summary.Updated != 0 {
+		t.Errorf("Expected a second backfill to change nothing, got %+v", summary)
+	}
+}
+
+func TestBackfillInAnyOrder(t *testing.T) {
+	repo := testRepository(t)
+
+	newer, newerData := testRelease("3.0", "2024-03-01", map[string]string{"2024-05-01": "Harvest"})
+	older, olderData := testRelease("2.9", "2024-01-01", map[string]string{"2024-05-01": "Loot"})
+
+	for _, backfill := range []struct {
+		release     dataRelease
+		almanaxData []dodumap.MappedMultilangNPCAlmanaxUnity
+	}{{newer, newerData}, {older, olderData}} {
+		if _, err := backfillRelease(repo, backfill.release, backfill.almanaxData, "2024-06-01"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := almanaxHistory(t, repo, "2024-05-01")
+	if len(history) != 2 {
+		t.Fatal("Expected 2 assignments, got ", len(history))
+	}
+	if history[0].Almanax.GameVersion != "2.9" || history[0].Almanax.DeletedAt == nil || !history[0].Almanax.DeletedAt.Equal(newer.releasedAt) {
+		t.Errorf("Expected the older release to be replaced by the newer one, got %s replaced at %v", history[0].Almanax.GameVersion, history[0].Almanax.DeletedAt)
+	}
+	if history[1].Almanax.GameVersion != "3.0" || history[1].Almanax.DeletedAt != nil {
+		t.Errorf("Expected the newer release to stay current, got %s replaced at %v", history[1].Almanax.GameVersion, history[1].Almanax.DeletedAt)
+	}
+}
@@ -0,0 +1,126 @@
+package almanax
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+)
+
+// GetAlmanaxNext answers when a tribute item (filter[item]) or a bonus type (filter[bonus_type]) comes up next. Along
+// with the next occurrences, it counts the occurrences per month over the next year of stored days.
+func GetAlmanaxNext(w http.ResponseWriter, r *http.Request) {
+	lang := r.Context().Value("lang").(string)
+	itemFilter := r.URL.Query().Get("filter[item]")
+	bonusType := r.URL.Query().Get("filter[bonus_type]")
+	timezone := r.URL.Query().Get("timezone")
+
+	if (itemFilter == "") == (bonusType == "") {
+		e.WriteInvalidQueryResponse(w, "Use exactly one of filter[item] and filter[bonus_type].")
+		return
+	}
+
+	var itemAnkamaId int64
+	var err error
+	if itemFilter != "" {
+		if itemAnkamaId, err = strconv.ParseInt(itemFilter, 10, 64); err != nil {
+			e.WriteInvalidQueryResponse(w, "Invalid item id.")
+			return
+		}
+	}
+
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	limit, err := getLimitInBoundary(r.URL.Query().Get("limit"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "Invalid limit value: "+err.Error())
+		return
+	}
+
+	today, err := clientToday(timezone)
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	fromStr := today.Format("2006-01-02")
+	toStr := today.AddDate(1, 0, 0).Format("2006-01-02")
+
+	almDb := repository(r)
+
+	var mappedAlmanax []database.MappedAlmanax
+	var monthCounts []database.AlmanaxMonthCount
+	if itemFilter != "" {
+		if mappedAlmanax, err = almDb.GetNextAlmanaxByItem(fromStr, itemAnkamaId, int(limit)); err == nil {
+			monthCounts, err = almDb.GetItemFrequency(fromStr, toStr, itemAnkamaId)
+		}
+	} else {
+		var found bool
+		if found, err = isValidBonusType(almDb, bonusType); err != nil {
+			e.WriteServerErrorResponse(w, "Could not get bonus types. "+err.Error())
+			return
+		}
+
+		if !found {
+			e.WriteInvalidQueryResponse(w, "Invalid bonus type.")
+			return
+		}
+
+		if mappedAlmanax, err = almDb.GetNextAlmanaxByNameID(fromStr, bonusType, int(limit)); err == nil {
+			monthCounts, err = almDb.GetBonusTypeFrequency(fromStr, toStr, bonusType)
+		}
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
+	if len(mappedAlmanax) == 0 {
+		e.WriteNotFoundResponse(w, "No upcoming Almanax found.")
+		return
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	res := AlmanaxNextResponse{
+		Occurrences: make([]AlmanaxResponse, 0, len(mappedAlmanax)),
+		Frequency: AlmanaxFrequency{
+			From:    fromStr,
+			To:      toStr,
+			ByMonth: make([]AlmanaxMonthFrequency, 0, len(monthCounts)),
+		},
+	}
+
+	for _, m := range mappedAlmanax {
+		response, err := renderAlmanaxResponse(&m, lang, levelInt, itemDb)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not render Almanax response. "+err.Error())
+			return
+		}
+		res.Occurrences = append(res.Occurrences, response)
+	}
+
+	for _, monthCount := range monthCounts {
+		res.Frequency.Total += monthCount.Count
+		res.Frequency.ByMonth = append(res.Frequency.ByMonth, AlmanaxMonthFrequency{
+			Month: monthCount.Month,
+			Count: monthCount.Count,
+		})
+	}
+
+	utils.RequestsTotal.Inc()
+
+	utils.WriteCacheHeader(&w)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
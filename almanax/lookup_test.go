@@ -0,0 +1,40 @@
+package almanax
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetLimitInBoundary(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected int64
+		valid    bool
+	}{
+		{"", 8, true},
+		{"0", 0, true},
+		{"100", 100, true},
+		{"101", 0, false},
+		{"-1", 0, false},
+		{"five", 0, false},
+	}
+	for _, test := range tests {
+		limit, err := getLimitInBoundary(test.raw)
+		if (err == nil) != test.valid || limit != test.expected {
+			t.Errorf("%q: expected %d valid %v, got %d and %v", test.raw, test.expected, test.valid, limit, err)
+		}
+	}
+}
+
+func TestGetAlmanaxNextRejectsNegativeLimit(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/en/almanax/next?filter[item]=289&limit=-1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "lang", "en"))
+	rec := httptest.NewRecorder()
+	GetAlmanaxNext(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a negative limit to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,224 @@
+package almanax
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	mapping "github.com/dofusdude/dodumap"
+	"github.com/hashicorp/go-memdb"
+)
+
+func memDbItemName(txn *memdb.Txn, ankamaId int, lang string) (string, error) {
+	raw, err := txn.First(fmt.Sprintf("%s-all_items", utils.CurrentRedBlueVersionStr(database.Version.MemDb)), "id", ankamaId)
+	if err != nil {
+		return "", err
+	}
+	if raw == nil {
+		return "", nil
+	}
+	return raw.(*mapping.MappedMultilangItemUnity).Name[lang], nil
+}
+
+func memDbRecipe(txn *memdb.Txn, ankamaId int) (*mapping.MappedMultilangRecipe, error) {
+	raw, err := txn.First(fmt.Sprintf("%s-recipes", utils.CurrentRedBlueVersionStr(database.Version.MemDb)), "id", ankamaId)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	return raw.(*mapping.MappedMultilangRecipe), nil
+}
+
+// buildShoppingList groups the tributes of all days by item and sums their quantities. Craftable tributes get their
+// recipe attached and the ingredients of all recipes are summed up as if every tribute was crafted.
+func buildShoppingList(mappedAlmanax []database.MappedAlmanax, lang string, txn *memdb.Txn) ([]ShoppingListItem, []ShoppingListIngredient, error) {
+	itemsById := make(map[int64]*ShoppingListItem)
+	var itemOrder []int64
+	for i := range mappedAlmanax {
+		response, err := renderAlmanaxResponse(&mappedAlmanax[i], lang, nil, txn)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		item, ok := itemsById[response.Tribute.Item.AnkamaId]
+		if !ok {
+			item = &ShoppingListItem{
+				AnkamaId:  response.Tribute.Item.AnkamaId,
+				Name:      response.Tribute.Item.Name,
+				Subtype:   response.Tribute.Item.Subtype,
+				ImageUrls: response.Tribute.Item.ImageUrls,
+				Dates:     make([]string, 0),
+			}
+			itemsById[item.AnkamaId] = item
+			itemOrder = append(itemOrder, item.AnkamaId)
+		}
+		item.Quantity += response.Tribute.Quantity
+		item.Dates = append(item.Dates, response.Date)
+	}
+
+	ingredientsById := make(map[int64]*ShoppingListIngredient)
+	items := make([]ShoppingListItem, 0, len(itemOrder))
+	for _, ankamaId := range itemOrder {
+		item := itemsById[ankamaId]
+
+		recipe, err := memDbRecipe(txn, int(ankamaId))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if recipe != nil {
+			for _, entry := range recipe.Entries {
+				name, err := memDbItemName(txn, entry.ItemId, lang)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				total := entry.Quantity * item.Quantity
+				item.Recipe = append(item.Recipe, ShoppingListRecipeEntry{
+					AnkamaId: int64(entry.ItemId),
+					Name:     name,
+					PerCraft: entry.Quantity,
+					Total:    total,
+				})
+
+				ingredient, ok := ingredientsById[int64(entry.ItemId)]
+				if !ok {
+					ingredient = &ShoppingListIngredient{
+						AnkamaId: int64(entry.ItemId),
+						Name:     name,
+					}
+					ingredientsById[ingredient.AnkamaId] = ingredient
+				}
+				ingredient.Total += total
+			}
+		}
+
+		items = append(items, *item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Quantity > items[j].Quantity
+	})
+
+	ingredients := make([]ShoppingListIngredient, 0, len(ingredientsById))
+	for _, ingredient := range ingredientsById {
+		ingredients = append(ingredients, *ingredient)
+	}
+	sort.Slice(ingredients, func(i, j int) bool {
+		if ingredients[i].Total != ingredients[j].Total {
+			return ingredients[i].Total > ingredients[j].Total
+		}
+		return ingredients[i].AnkamaId < ingredients[j].AnkamaId
+	})
+
+	return items, ingredients, nil
+}
+
+func loadShoppingList(w http.ResponseWriter, r *http.Request) (*ShoppingListResponse, bool) {
+	lang := r.Context().Value("lang").(string)
+	bonusType := r.URL.Query().Get("filter[bonus_type]")
+
+	fromDate, toDate, err := parseAlmanaxRange(r.URL.Query())
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return nil, false
+	}
+
+	almDb := repository(r)
+
+	if bonusType != "" {
+		found, err := isValidBonusType(almDb, bonusType)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not get bonus types. "+err.Error())
+			return nil, false
+		}
+
+		if !found {
+			e.WriteInvalidQueryResponse(w, "Invalid bonus type.")
+			return nil, false
+		}
+	}
+
+	fromDateStr := fromDate.Format("2006-01-02")
+	toDateStr := toDate.Format("2006-01-02")
+
+	var mappedAlmanax []database.MappedAlmanax
+	if bonusType != "" {
+		mappedAlmanax, err = almDb.GetAlmanaxByDateRangeAndNameID(fromDateStr, toDateStr, bonusType)
+	} else {
+		mappedAlmanax, err = almDb.GetAlmanaxByDateRange(fromDateStr, toDateStr)
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return nil, false
+	}
+
+	if len(mappedAlmanax) == 0 {
+		e.WriteNotFoundResponse(w, "No Almanax found.")
+		return nil, false
+	}
+
+	itemDb := database.Db.Txn(false)
+	defer itemDb.Abort()
+
+	items, ingredients, err := buildShoppingList(mappedAlmanax, lang, itemDb)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not build shopping list. "+err.Error())
+		return nil, false
+	}
+
+	utils.RequestsTotal.Inc()
+
+	return &ShoppingListResponse{
+		From:        fromDateStr,
+		To:          toDateStr,
+		Items:       items,
+		Ingredients: ingredients,
+	}, true
+}
+
+// GetAlmanaxShoppingList sums up the tributes of a date range. It takes the same parameters as GetAlmanaxRange.
+func GetAlmanaxShoppingList(w http.ResponseWriter, r *http.Request) {
+	shoppingList, ok := loadShoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteCacheHeader(&w)
+	if err := json.NewEncoder(w).Encode(shoppingList); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+// GetAlmanaxShoppingListCsv exports the shopping list with one row per tribute and one per recipe ingredient total.
+func GetAlmanaxShoppingListCsv(w http.ResponseWriter, r *http.Request) {
+	shoppingList, ok := loadShoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="almanax-shopping-list.csv"`)
+
+	writer := csv.NewWriter(w)
+	records := [][]string{{"kind", "ankama_id", "name", "quantity"}}
+	for _, item := range shoppingList.Items {
+		records = append(records, []string{"tribute", strconv.FormatInt(item.AnkamaId, 10), item.Name, strconv.Itoa(item.Quantity)})
+	}
+	for _, ingredient := range shoppingList.Ingredients {
+		records = append(records, []string{"ingredient", strconv.FormatInt(ingredient.AnkamaId, 10), ingredient.Name, strconv.Itoa(ingredient.Total)})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return
+	}
+}
@@ -0,0 +1,127 @@
+package almanax
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dofusdude/doduapi/database"
+	"github.com/dofusdude/doduapi/utils"
+	mapping "github.com/dofusdude/dodumap"
+	"github.com/hashicorp/go-memdb"
+)
+
+// testItemDb holds items and recipes in the active generation, like the tables the indexing fills.
+func testItemDb(t *testing.T, items []mapping.MappedMultilangItemUnity, recipes []mapping.MappedMultilangRecipe) *memdb.MemDB {
+	t.Helper()
+
+	generation := utils.CurrentRedBlueVersionStr(database.Version.MemDb)
+	table := func(name string, field string) *memdb.TableSchema {
+		return &memdb.TableSchema{
+			Name: name,
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": {Name: "id", Unique: true, Indexer: &memdb.IntFieldIndex{Field: field}},
+			},
+		}
+	}
+	schema := &memdb.DBSchema{Tables: map[string]*memdb.TableSchema{}}
+	for _, name := range []string{"all_items", "equipment", "resources"} {
+		schema.Tables[generation+"-"+name] = table(generation+"-"+name, "AnkamaId")
+	}
+	schema.Tables[generation+"-recipes"] = table(generation+"-recipes", "ResultId")
+
+	db, err := memdb.NewMemDB(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := db.Txn(true)
+	for i := range items {
+		if err = txn.Insert(generation+"-all_items", &items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range recipes {
+		if err = txn.Insert(generation+"-recipes", &recipes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	txn.Commit()
+
+	return db
+}
+
+func testTributeDay(date string, itemId int64, quantity int) database.MappedAlmanax {
+	var m database.MappedAlmanax
+	m.Almanax.Date = date
+	m.Tribute.ItemAnkamaID = itemId
+	m.Tribute.ItemNameEn = fmt.Sprintf("Item %d", itemId)
+	m.Tribute.ItemCategoryId = 2
+	m.Tribute.Quantity = quantity
+	return m
+}
+
+func TestBuildShoppingList(t *testing.T) {
+	names := map[int]string{1: "Bread", 2: "Potion", 10: "Wheat", 11: "Water", 20: "Flower"}
+	items := make([]mapping.MappedMultilangItemUnity, 0, len(names))
+	for id, name := range names {
+		items = append(items, mapping.MappedMultilangItemUnity{AnkamaId: id, Name: map[string]string{"en": name}})
+	}
+	recipes := []mapping.MappedMultilangRecipe{
+		{ResultId: 1, Entries: []mapping.MappedMultilangRecipeEntry{{ItemId: 10, Quantity: 2}, {ItemId: 11, Quantity: 1}}},
+		{ResultId: 2, Entries: []mapping.MappedMultilangRecipeEntry{{ItemId: 11, Quantity: 3}, {ItemId: 20, Quantity: 1}}},
+	}
+	db := testItemDb(t, items, recipes)
+	txn := db.Txn(false)
+	defer txn.Abort()
+
+	days := []database.MappedAlmanax{
+		testTributeDay("2024-05-01", 1, 3),
+		testTributeDay("2024-05-02", 2, 2),
+		testTributeDay("2024-05-03", 1, 2),
+		testTributeDay("2024-05-04", 99, 7), // not craftable
+	}
+	shoppingItems, ingredients, err := buildShoppingList(days, "en", txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tribute struct {
+		id       int64
+		quantity int
+		dates    []string
+	}
+	var gotItems []tribute
+	for _, item := range shoppingItems {
+		gotItems = append(gotItems, tribute{item.AnkamaId, item.Quantity, item.Dates})
+	}
+	expectedItems := []tribute{
+		{99, 7, []string{"2024-05-04"}},
+		{1, 5, []string{"2024-05-01", "2024-05-03"}},
+		{2, 2, []string{"2024-05-02"}},
+	}
+	if !reflect.DeepEqual(gotItems, expectedItems) {
+		t.Errorf("Expected tributes %v, got %v", expectedItems, gotItems)
+	}
+
+	expectedBreadRecipe := []ShoppingListRecipeEntry{
+		{AnkamaId: 10, Name: "Wheat", PerCraft: 2, Total: 10},
+		{AnkamaId: 11, Name: "Water", PerCraft: 1, Total: 5},
+	}
+	if !reflect.DeepEqual(shoppingItems[1].Recipe, expectedBreadRecipe) {
+		t.Errorf("Expected bread recipe %v, got %v", expectedBreadRecipe, shoppingItems[1].Recipe)
+	}
+	if shoppingItems[0].Recipe != nil {
+		t.Error("Expected no recipe for an item that can not be crafted")
+	}
+
+	// water is needed by both recipes: 5 for the bread and 6 for the potions
+	expectedIngredients := []ShoppingListIngredient{
+		{AnkamaId: 11, Name: "Water", Total: 11},
+		{AnkamaId: 10, Name: "Wheat", Total: 10},
+		{AnkamaId: 20, Name: "Flower", Total: 2},
+	}
+	if !reflect.DeepEqual(ingredients, expectedIngredients) {
+		t.Errorf("Expected ingredients %v, got %v", expectedIngredients, ingredients)
+	}
+}
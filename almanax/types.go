@@ -1,5 +1,7 @@
 package almanax
 
+import "time"
+
 const (
 	TwitterWebhookType string = "twitter"
 	RSSWebhookType            = "rss"
@@ -28,9 +30,115 @@ type AlmanaxResponse struct {
 	} `json:"tribute"`
 }
 
+type AlmanaxXpDay struct {
+	Date            string  `json:"date"`
+	ExperienceRatio float64 `json:"experience_ratio"`
+	OptimalLevel    int     `json:"optimal_level"`
+	Duration        float64 `json:"duration"`
+	XpByLevel       []int   `json:"xp_by_level"` // index 0 is level 1
+}
+
+type AlmanaxXpBestDay struct {
+	Date string `json:"date"`
+	Xp   int    `json:"xp"`
+}
+
+type AlmanaxXpResponse struct {
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Level    *int               `json:"level,omitempty"`
+	Days     []AlmanaxXpDay     `json:"days"`
+	BestDays []AlmanaxXpBestDay `json:"best_days,omitempty"`
+}
+
+type AlmanaxGameDayResponse struct {
+	AlmanaxResponse
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+type AlmanaxMonthFrequency struct {
+	Month string `json:"month"` // YYYY-MM
+	Count int    `json:"count"`
+}
+
+type AlmanaxFrequency struct {
+	From    string                  `json:"from"`
+	To      string                  `json:"to"`
+	Total   int                     `json:"total"`
+	ByMonth []AlmanaxMonthFrequency `json:"by_month"`
+}
+
+type AlmanaxNextResponse struct {
+	Occurrences []AlmanaxResponse `json:"occurrences"`
+	Frequency   AlmanaxFrequency  `json:"frequency"`
+}
+
+// ShoppingListRecipeEntry is an ingredient of a tribute recipe.
+type ShoppingListRecipeEntry struct {
+	AnkamaId int64  `json:"ankama_id"`
+	Name     string `json:"name"`
+	PerCraft int    `json:"per_craft"`
+	Total    int    `json:"total"` // for crafting every tribute of this item in the range
+}
+
+// ShoppingListIngredient is the sum of an ingredient over all tribute recipes.
+type ShoppingListIngredient struct {
+	AnkamaId int64  `json:"ankama_id"`
+	Name     string `json:"name"`
+	Total    int    `json:"total"`
+}
+
+type ShoppingListItem struct {
+	AnkamaId  int64                     `json:"ankama_id"`
+	Name      string                    `json:"name"`
+	Subtype   string                    `json:"subtype"`
+	ImageUrls ApiImageUrls              `json:"image_urls"`
+	Quantity  int                       `json:"quantity"`
+	Dates     []string                  `json:"dates"`
+	Recipe    []ShoppingListRecipeEntry `json:"recipe,omitempty"`
+}
+
+type ShoppingListResponse struct {
+	From        string                   `json:"from"`
+	To          string                   `json:"to"`
+	Items       []ShoppingListItem       `json:"items"`
+	Ingredients []ShoppingListIngredient `json:"ingredients"` // everything needed to craft all craftable tributes
+}
+
+type AlmanaxHistoryEntry struct {
+	GameVersion string          `json:"game_version"`
+	ValidFrom   time.Time       `json:"valid_from"`
+	ReplacedAt  *time.Time      `json:"replaced_at"`
+	Almanax     AlmanaxResponse `json:"almanax"`
+}
+
 type AlmanaxBonusListing struct {
 	Id   string `json:"id"`   // english-id
 	Name string `json:"name"` // translated text
+	Url  string `json:"url"`  // bonus detail
+}
+
+type AlmanaxBonusVariant struct {
+	Description string `json:"description"`
+	Count       int    `json:"count"`
+}
+
+type AlmanaxBonusTribute struct {
+	AnkamaId  int64        `json:"ankama_id"`
+	Name      string       `json:"name"`
+	Subtype   string       `json:"subtype"`
+	ImageUrls ApiImageUrls `json:"image_urls"`
+	Count     int          `json:"count"`
+}
+
+type AlmanaxBonusDetail struct {
+	Id              string                `json:"id"`
+	Name            string                `json:"name"`
+	Variants        []AlmanaxBonusVariant `json:"variants"`
+	Occurrences     AlmanaxFrequency      `json:"occurrences"`
+	Next            *AlmanaxResponse      `json:"next"`
+	TypicalTributes []AlmanaxBonusTribute `json:"typical_tributes"`
 }
 
 type AlmanaxBonusListingMeili struct {
@@ -39,6 +147,35 @@ type AlmanaxBonusListingMeili struct {
 	Name string `json:"name"` // translated text
 }
 
+type WebhookRequest struct {
+	Format     string   `json:"format"`
+	Url        string   `json:"url"`
+	Lang       string   `json:"lang"`
+	BonusTypes []string `json:"bonus_types"`
+}
+
+type WebhookResponse struct {
+	Id                int64      `json:"id"`
+	Format            string     `json:"format"`
+	Url               string     `json:"url"`
+	Lang              string     `json:"lang"`
+	BonusTypes        []string   `json:"bonus_types"`
+	LastDeliveredDate *string    `json:"last_delivered_date"`
+	FailedDays        int        `json:"failed_days"`
+	DisabledAt        *time.Time `json:"disabled_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	Date       string    `json:"date"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 type ApiImageUrls struct {
 	Icon string `json:"icon"`
 	Sd   string `json:"sd,omitempty"`
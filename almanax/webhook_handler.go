@@ -0,0 +1,276 @@
+package almanax
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+const webhookDeliveriesLimit = 50
+
+func renderWebhook(webhook *database.Webhook) WebhookResponse {
+	return WebhookResponse{
+		Id:                webhook.ID,
+		Format:            webhook.Format,
+		Url:               webhook.Url,
+		Lang:              webhook.Lang,
+		BonusTypes:        webhook.BonusTypes,
+		LastDeliveredDate: webhook.LastDeliveredDate,
+		FailedDays:        webhook.FailedDays,
+		DisabledAt:        webhook.DisabledAt,
+		CreatedAt:         webhook.CreatedAt,
+		UpdatedAt:         webhook.UpdatedAt,
+	}
+}
+
+func validateWebhookRequest(req *WebhookRequest, almDb *database.Repository) error {
+	if !slices.Contains(webhookFormats, req.Format) {
+		return fmt.Errorf("format must be one of %v", webhookFormats)
+	}
+
+	parsedUrl, err := url.Parse(req.Url)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https url")
+	}
+
+	if !slices.Contains(config.Languages, req.Lang) {
+		return fmt.Errorf("lang must be one of %v", config.Languages)
+	}
+
+	if len(req.BonusTypes) == 0 {
+		return nil
+	}
+
+	bonusTypes, err := almDb.GetBonusTypes()
+	if err != nil {
+		return err
+	}
+
+	for _, requested := range req.BonusTypes {
+		if !slices.ContainsFunc(bonusTypes, func(b database.BonusType) bool { return b.NameID == requested }) {
+			return fmt.Errorf("invalid bonus type %s", requested)
+		}
+	}
+
+	return nil
+}
+
+func webhookIdParam(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "webhookId"), 10, 64)
+}
+
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request, almDb *database.Repository) (*WebhookRequest, bool) {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		e.WriteInvalidJsonResponse(w, err.Error())
+		return nil, false
+	}
+	if req.BonusTypes == nil {
+		req.BonusTypes = []string{}
+	}
+
+	if err := validateWebhookRequest(&req, almDb); err != nil {
+		e.WriteInvalidJsonResponse(w, err.Error())
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	almDb := repository(r)
+
+	req, ok := decodeWebhookRequest(w, r, almDb)
+	if !ok {
+		return
+	}
+
+	id, err := almDb.CreateWebhook(&database.Webhook{
+		Format:     req.Format,
+		Url:        req.Url,
+		Lang:       req.Lang,
+		BonusTypes: req.BonusTypes,
+	})
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not create webhook. "+err.Error())
+		return
+	}
+
+	webhook, err := almDb.GetWebhook(id)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get created webhook. "+err.Error())
+		return
+	}
+
+	utils.SetJsonHeader(&w)
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(renderWebhook(&webhook)); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	almDb := repository(r)
+
+	webhooks, err := almDb.GetWebhooks()
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get webhooks. "+err.Error())
+		return
+	}
+
+	res := make([]WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		res = append(res, renderWebhook(&webhook))
+	}
+
+	utils.SetJsonHeader(&w)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIdParam(r)
+	if err != nil {
+		e.WriteInvalidUrlResponse(w, "Invalid webhook id.")
+		return
+	}
+
+	almDb := repository(r)
+
+	webhook, err := almDb.GetWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.WriteNotFoundResponse(w, "Webhook not found.")
+		return
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get webhook. "+err.Error())
+		return
+	}
+
+	utils.SetJsonHeader(&w)
+	if err = json.NewEncoder(w).Encode(renderWebhook(&webhook)); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIdParam(r)
+	if err != nil {
+		e.WriteInvalidUrlResponse(w, "Invalid webhook id.")
+		return
+	}
+
+	almDb := repository(r)
+
+	req, ok := decodeWebhookRequest(w, r, almDb)
+	if !ok {
+		return
+	}
+
+	err = almDb.UpdateWebhook(&database.Webhook{
+		ID:         id,
+		Format:     req.Format,
+		Url:        req.Url,
+		Lang:       req.Lang,
+		BonusTypes: req.BonusTypes,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		e.WriteNotFoundResponse(w, "Webhook not found.")
+		return
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not update webhook. "+err.Error())
+		return
+	}
+
+	webhook, err := almDb.GetWebhook(id)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get updated webhook. "+err.Error())
+		return
+	}
+
+	utils.SetJsonHeader(&w)
+	if err = json.NewEncoder(w).Encode(renderWebhook(&webhook)); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIdParam(r)
+	if err != nil {
+		e.WriteInvalidUrlResponse(w, "Invalid webhook id.")
+		return
+	}
+
+	almDb := repository(r)
+
+	err = almDb.DeleteWebhook(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.WriteNotFoundResponse(w, "Webhook not found.")
+		return
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not delete webhook. "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookIdParam(r)
+	if err != nil {
+		e.WriteInvalidUrlResponse(w, "Invalid webhook id.")
+		return
+	}
+
+	almDb := repository(r)
+
+	if _, err = almDb.GetWebhook(id); errors.Is(err, sql.ErrNoRows) {
+		e.WriteNotFoundResponse(w, "Webhook not found.")
+		return
+	} else if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get webhook. "+err.Error())
+		return
+	}
+
+	deliveries, err := almDb.GetWebhookDeliveries(id, webhookDeliveriesLimit)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Could not get webhook deliveries. "+err.Error())
+		return
+	}
+
+	res := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, WebhookDeliveryResponse{
+			Date:       delivery.Date,
+			Attempt:    delivery.Attempt,
+			StatusCode: delivery.StatusCode,
+			Error:      delivery.Error,
+			Success:    delivery.Success,
+			CreatedAt:  delivery.CreatedAt,
+		})
+	}
+
+	utils.SetJsonHeader(&w)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
@@ -0,0 +1,274 @@
+package almanax
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/database"
+)
+
+const (
+	DiscordWebhookFormat = "discord"
+	JSONWebhookFormat    = "json"
+)
+
+var webhookFormats = []string{DiscordWebhookFormat, JSONWebhookFormat}
+
+var offeringLabels = map[string]string{
+	"en": "Offering",
+	"fr": "Offrande",
+	"de": "Opfergabe",
+	"es": "Ofrenda",
+	"pt": "Oferenda",
+}
+
+type webhookJSONPayload struct {
+	Type string          `json:"type"`
+	Lang string          `json:"lang"`
+	Data AlmanaxResponse `json:"data"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Fields      []discordEmbedField `json:"fields"`
+	Thumbnail   *struct {
+		Url string `json:"url"`
+	} `json:"thumbnail,omitempty"`
+}
+
+type discordPayload struct {
+	Username string         `json:"username"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+func webhookPayload(webhook *database.Webhook, almanax *AlmanaxResponse) ([]byte, error) {
+	switch webhook.Format {
+	case DiscordWebhookFormat:
+		embed := discordEmbed{
+			Title:       fmt.Sprintf("Almanax %s", almanax.Date),
+			Description: almanax.Bonus.Description,
+			Fields: []discordEmbedField{
+				{Name: "Bonus", Value: almanax.Bonus.BonusType.Name, Inline: true},
+				{Name: offeringLabels[webhook.Lang], Value: fmt.Sprintf("%dx %s", almanax.Tribute.Quantity, almanax.Tribute.Item.Name), Inline: true},
+			},
+		}
+		if almanax.Tribute.Item.ImageUrls.Icon != "" {
+			embed.Thumbnail = &struct {
+				Url string `json:"url"`
+			}{Url: almanax.Tribute.Item.ImageUrls.Icon}
+		}
+		return json.Marshal(discordPayload{
+			Username: "Almanax",
+			Embeds:   []discordEmbed{embed},
+		})
+	case JSONWebhookFormat:
+		return json.Marshal(webhookJSONPayload{
+			Type: AlmanaxWebhookType,
+			Lang: webhook.Lang,
+			Data: *almanax,
+		})
+	default:
+		return nil, fmt.Errorf("unknown webhook format %s", webhook.Format)
+	}
+}
+
+// WebhookDispatcher posts almanax payloads to subscribed webhooks. Every attempt is logged in the database.
+type WebhookDispatcher struct {
+	Client       *http.Client
+	MaxAttempts  int
+	Backoff      time.Duration // doubled after every failed attempt
+	Parallel     int           // webhooks delivered at the same time, so one slow webhook does not hold up the others
+	DayAttempts  int           // failed attempts a webhook gets per day over all runs, one run of MaxAttempts if 0
+	DisableAfter int           // days in a row a webhook may fail before it is disabled, 0 keeps it enabled
+}
+
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts:  5,
+		Backoff:      2 * time.Second,
+		Parallel:     8,
+		DayAttempts:  15,
+		DisableAfter: 7,
+	}
+}
+
+// retryableStatus reports whether a failed delivery is worth another try. Client errors except rate limiting are not.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Deliver posts the almanax to the webhook, retrying with exponential backoff. It returns the error of the last
+// attempt if none succeeded.
+func (d *WebhookDispatcher) Deliver(repo *database.Repository, webhook *database.Webhook, almanax *AlmanaxResponse) error {
+	return d.deliver(repo, webhook, almanax, d.MaxAttempts)
+}
+
+func (d *WebhookDispatcher) deliver(repo *database.Repository, webhook *database.Webhook, almanax *AlmanaxResponse, maxAttempts int) error {
+	payload, err := webhookPayload(webhook, almanax)
+	if err != nil {
+		return err
+	}
+
+	backoff := d.Backoff
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		delivery := database.WebhookDelivery{
+			WebhookID: webhook.ID,
+			Date:      almanax.Date,
+			Attempt:   attempt,
+		}
+
+		var resp *http.Response
+		resp, err = d.Client.Post(webhook.Url, "application/json", bytes.NewReader(payload))
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			delivery.StatusCode = resp.StatusCode
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+			}
+		}
+
+		delivery.Success = err == nil
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if _, logErr := repo.CreateWebhookDelivery(&delivery); logErr != nil {
+			log.Error("could not log webhook delivery", "webhook", webhook.ID, "err", logErr)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if delivery.StatusCode != 0 && !retryableStatus(delivery.StatusCode) {
+			return err
+		}
+
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return err
+}
+
+// DispatchAlmanax delivers the almanax of date to all webhooks that did not get it yet. A webhook is only marked for
+// the day once its delivery worked, so failed ones are tried again on the next run until they spent their attempts of
+// the day. Webhooks filtering for other bonus types are skipped but still marked, so they are not checked again on the
+// same day.
+func (d *WebhookDispatcher) DispatchAlmanax(repo *database.Repository, date string, render func(m *database.MappedAlmanax, lang string) (AlmanaxResponse, error)) error {
+	webhooks, err := repo.GetWebhooksDueForDate(date)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	mappedAlmanax, err := repo.GetAlmanaxByDateRange(date, date)
+	if err != nil {
+		return err
+	}
+	if len(mappedAlmanax) != 1 {
+		return fmt.Errorf("expected one almanax for %s, found %d", date, len(mappedAlmanax))
+	}
+
+	dayAttempts := d.DayAttempts
+	if dayAttempts <= 0 {
+		dayAttempts = d.MaxAttempts
+	}
+
+	parallel := max(d.Parallel, 1)
+	semaphore := make(chan struct{}, parallel)
+	errs := make(chan error, len(webhooks)) // every webhook reports one error at most
+	var wg sync.WaitGroup
+	for _, webhook := range webhooks {
+		if len(webhook.BonusTypes) != 0 && !slices.Contains(webhook.BonusTypes, mappedAlmanax[0].BonusType.NameID) {
+			if err := repo.MarkWebhookDelivered(webhook.ID, date); err != nil {
+				errs <- err
+			}
+			continue
+		}
+
+		failed, err := repo.CountFailedWebhookDeliveries(webhook.ID, date)
+		if err != nil {
+			errs <- err
+			continue
+		}
+		remaining := dayAttempts - failed
+		if remaining <= 0 {
+			disabled, err := repo.MarkWebhookFailed(webhook.ID, date, d.DisableAfter)
+			if err != nil {
+				errs <- err
+				continue
+			}
+			log.Warn("webhook spent its attempts for the day", "webhook", webhook.ID, "date", date, "disabled", disabled)
+			continue
+		}
+
+		response, err := render(&mappedAlmanax[0], webhook.Lang)
+		if err != nil {
+			errs <- err
+			continue
+		}
+
+		wg.Add(1)
+		go func(webhook database.Webhook, response AlmanaxResponse) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if err := d.deliver(repo, &webhook, &response, min(d.MaxAttempts, remaining)); err != nil {
+				log.Warn("webhook delivery failed, trying again with the next run", "webhook", webhook.ID, "date", date, "err", err)
+				return
+			}
+			if err := repo.MarkWebhookDelivered(webhook.ID, date); err != nil {
+				errs <- err
+			}
+		}(webhook, response)
+	}
+	wg.Wait()
+	close(errs)
+
+	var dispatchErrs []error
+	for err := range errs {
+		dispatchErrs = append(dispatchErrs, err)
+	}
+	return errors.Join(dispatchErrs...)
+}
+
+// RunWebhookScheduler checks for due webhooks every interval and posts the almanax of the current day to them.
+func RunWebhookScheduler(repo *database.Repository, interval time.Duration) {
+	dispatcher := NewWebhookDispatcher()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		date := newGameClock().today().Format("2006-01-02")
+		err := dispatcher.DispatchAlmanax(repo, date, func(m *database.MappedAlmanax, lang string) (AlmanaxResponse, error) {
+			txn := database.Db.Txn(false)
+			defer txn.Abort()
+			return renderAlmanaxResponse(m, lang, nil, txn)
+		})
+		if err != nil {
+			log.Error("could not dispatch almanax webhooks", "date", date, "err", err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package almanax
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/database"
+	"github.com/dofusdude/dodumap"
+)
+
+func testRepository(t *testing.T) *database.Repository {
+	t.Helper()
+
+	repo, err := database.OpenRepository(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	if _, err = repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func testAlmanax(bonusType string) *dodumap.MappedMultilangNPCAlmanaxUnity {
+	almanax := &dodumap.MappedMultilangNPCAlmanaxUnity{
+		Bonus:       map[string]string{"en": "More loot.", "fr": "Plus de butin."},
+		BonusType:   map[string]string{"en": bonusType, "fr": bonusType},
+		RewardKamas: 1000,
+	}
+	almanax.Offering.ItemId = 289
+	almanax.Offering.ItemName = map[string]string{"en": "Wheat", "fr": "Blé"}
+	almanax.Offering.Quantity = 3
+	return almanax
+}
+
+func TestWebhookDeliveryRetriesUntilSuccess(t *testing.T) {
+	repo := testRepository(t)
+
+	var calls atomic.Int32
+	var received discordPayload
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer standIn.Close()
+
+	webhookId, err := repo.CreateWebhook(&database.Webhook{Format: DiscordWebhookFormat, Url: standIn.URL, Lang: "fr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := repo.GetWebhook(webhookId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := &WebhookDispatcher{Client: standIn.Client(), MaxAttempts: 5, Backoff: time.Millisecond}
+	almanax := AlmanaxResponse{Date: "2024-05-01"}
+	almanax.Tribute.Item.Name = "Blé"
+	almanax.Tribute.Quantity = 3
+	if err = dispatcher.Deliver(repo, &webhook, &almanax); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls.Load() != 3 {
+		t.Error("Expected 3 attempts, got ", calls.Load())
+	}
+	if len(received.Embeds) != 1 || received.Embeds[0].Fields[1].Value != "3x Blé" || received.Embeds[0].Fields[1].Name != "Offrande" {
+		t.Error("Unexpected discord payload ", received)
+	}
+
+	deliveries, err := repo.GetWebhookDeliveries(webhookId, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 3 || !deliveries[0].Success || deliveries[1].Success || deliveries[1].StatusCode != http.StatusServiceUnavailable {
+		t.Error("Unexpected delivery log ", deliveries)
+	}
+}
+
+func TestWebhookDeliveryStopsOnClientError(t *testing.T) {
+	repo := testRepository(t)
+
+	var calls atomic.Int32
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer standIn.Close()
+
+	webhook := database.Webhook{Format: JSONWebhookFormat, Url: standIn.URL, Lang: "en"}
+	var err error
+	if webhook.ID, err = repo.CreateWebhook(&webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := &WebhookDispatcher{Client: standIn.Client(), MaxAttempts: 5, Backoff: time.Millisecond}
+	if err = dispatcher.Deliver(repo, &webhook, &AlmanaxResponse{Date: "2024-05-01"}); err == nil {
+		t.Error("Expected an error for a gone webhook")
+	}
+	if calls.Load() != 1 {
+		t.Error("Expected no retries on 404, got attempts: ", calls.Load())
+	}
+}
+
+func TestDispatchAlmanaxFiltersBonusTypes(t *testing.T) {
+	repo := testRepository(t)
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{"2024-05-01": *testAlmanax("Loot")}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var received []webhookJSONPayload
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookJSONPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		received = append(received, payload)
+	}))
+	defer standIn.Close()
+
+	matching, _ := repo.CreateWebhook(&database.Webhook{Format: JSONWebhookFormat, Url: standIn.URL, Lang: "en", BonusTypes: []string{"loot"}})
+	other, _ := repo.CreateWebhook(&database.Webhook{Format: JSONWebhookFormat, Url: standIn.URL, Lang: "en", BonusTypes: []string{"experience"}})
+
+	render := func(m *database.MappedAlmanax, lang string) (AlmanaxResponse, error) {
+		var response AlmanaxResponse
+		response.Date = m.Almanax.Date
+		response.Bonus.BonusType.Id = m.BonusType.NameID
+		return response, nil
+	}
+
+	dispatcher := &WebhookDispatcher{Client: standIn.Client(), MaxAttempts: 1, Backoff: time.Millisecond}
+	for i := 0; i < 2; i++ { // the second run must not deliver again
+		if err := dispatcher.DispatchAlmanax(repo, "2024-05-01", render); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(received) != 1 || received[0].Type != AlmanaxWebhookType || received[0].Data.Bonus.BonusType.Id != "loot" {
+		t.Error("Expected exactly one almanax payload, got ", received)
+	}
+
+	for _, id := range []int64{matching, other} {
+		webhook, err := repo.GetWebhook(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if webhook.LastDeliveredDate == nil || *webhook.LastDeliveredDate != "2024-05-01" {
+			t.Error("Expected webhook to be marked for the day ", id)
+		}
+	}
+}
+
+func TestDispatchAlmanaxRetriesFailedWebhooks(t *testing.T) {
+	repo := testRepository(t)
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{"2024-05-01": *testAlmanax("Loot")}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var down atomic.Bool
+	down.Store(true)
+	var delivered atomic.Int32
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		delivered.Add(1)
+	}))
+	defer standIn.Close()
+
+	webhookId, err := repo.CreateWebhook(&database.Webhook{Format: JSONWebhookFormat, Url: standIn.URL, Lang: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(m *database.MappedAlmanax, lang string) (AlmanaxResponse, error) {
+		return AlmanaxResponse{Date: m.Almanax.Date}, nil
+	}
+	dispatcher := &WebhookDispatcher{Client: standIn.Client(), MaxAttempts: 2, Backoff: time.Millisecond, Parallel: 2, DayAttempts: 4}
+
+	if err = dispatcher.DispatchAlmanax(repo, "2024-05-01", render); err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := repo.GetWebhook(webhookId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhook.LastDeliveredDate != nil {
+		t.Fatal("Expected a failed webhook to stay due, got ", *webhook.LastDeliveredDate)
+	}
+
+	down.Store(false)
+	for i := 0; i < 2; i++ { // the second run must not deliver again
+		if err = dispatcher.DispatchAlmanax(repo, "2024-05-01", render); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if delivered.Load() != 1 {
+		t.Error("Expected one delivery once the webhook is back, got ", delivered.Load())
+	}
+}
+
+func TestDispatchAlmanaxGivesUpFailingWebhooks(t *testing.T) {
+	repo := testRepository(t)
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-05-01": *testAlmanax("Loot"),
+		"2024-05-02": *testAlmanax("Loot"),
+	}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer standIn.Close()
+
+	webhookId, err := repo.CreateWebhook(&database.Webhook{Format: JSONWebhookFormat, Url: standIn.URL, Lang: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(m *database.MappedAlmanax, lang string) (AlmanaxResponse, error) {
+		return AlmanaxResponse{Date: m.Almanax.Date}, nil
+	}
+	dispatcher := &WebhookDispatcher{Client: standIn.Client(), MaxAttempts: 2, Backoff: time.Millisecond, DayAttempts: 3, DisableAfter: 2}
+
+	for _, date := range []string{"2024-05-01", "2024-05-02"} {
+		for i := 0; i < 4; i++ {
+			if err = dispatcher.DispatchAlmanax(repo, date, render); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if calls.Load() != 6 {
+		t.Error("Expected 3 attempts per day, got ", calls.Load())
+	}
+
+	webhook, err := repo.GetWebhook(webhookId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhook.FailedDays != 2 || webhook.DisabledAt == nil {
+		t.Errorf("Expected the webhook to be disabled after 2 failed days, got %d failed days", webhook.FailedDays)
+	}
+
+	if err = repo.UpdateWebhook(&webhook); err != nil {
+		t.Fatal(err)
+	}
+	if due, err := repo.GetWebhooksDueForDate("2024-05-03"); err != nil || len(due) != 1 {
+		t.Error("Expected an updated webhook to be enabled again, got ", due, err)
+	}
+}
@@ -0,0 +1,114 @@
+package almanax
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+)
+
+const maxCharacterLevel = 200
+
+func experienceCurve(m *database.MappedAlmanax) []int {
+	curve := make([]int, maxCharacterLevel)
+	for level := 1; level <= maxCharacterLevel; level++ {
+		curve[level-1] = experienceReward(level, m.Almanax.OptimalLvl, m.Almanax.XpRatio, m.Almanax.Duration)
+	}
+	return curve
+}
+
+// bestExperienceDays ranks the days by their experience reward for level, highest first and earlier days first on ties.
+// A negative limit keeps no days.
+func bestExperienceDays(days []AlmanaxXpDay, level int, limit int) []AlmanaxXpBestDay {
+	limit = max(limit, 0)
+
+	best := make([]AlmanaxXpBestDay, 0, len(days))
+	for _, day := range days {
+		best = append(best, AlmanaxXpBestDay{
+			Date: day.Date,
+			Xp:   day.XpByLevel[level-1],
+		})
+	}
+
+	sort.SliceStable(best, func(i, j int) bool {
+		return best[i].Xp > best[j].Xp
+	})
+
+	if len(best) > limit {
+		best = best[:limit]
+	}
+	return best
+}
+
+// GetAlmanaxXp returns the experience reward of every level for a single date or a date range, with the raw values of
+// the formula. With a level, it also ranks the best days of the range for that level.
+func GetAlmanaxXp(w http.ResponseWriter, r *http.Request) {
+	levelInt, err := parseLevelParam(r.URL.Query().Get("level"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	limit, err := getLimitInBoundary(r.URL.Query().Get("limit"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "Invalid limit value: "+err.Error())
+		return
+	}
+
+	var fromDate, toDate time.Time
+	if date, ok := r.Context().Value("date").(time.Time); ok {
+		fromDate, toDate = date, date
+	} else if fromDate, toDate, err = parseAlmanaxRange(r.URL.Query()); err != nil {
+		e.WriteInvalidQueryResponse(w, err.Error())
+		return
+	}
+
+	almDb := repository(r)
+
+	fromDateStr := fromDate.Format("2006-01-02")
+	toDateStr := toDate.Format("2006-01-02")
+	mappedAlmanax, err := almDb.GetAlmanaxByDateRange(fromDateStr, toDateStr)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
+	if len(mappedAlmanax) == 0 {
+		e.WriteNotFoundResponse(w, "No Almanax found.")
+		return
+	}
+
+	res := AlmanaxXpResponse{
+		From: fromDateStr,
+		To:   toDateStr,
+		Days: make([]AlmanaxXpDay, 0, len(mappedAlmanax)),
+	}
+
+	for i := range mappedAlmanax {
+		m := &mappedAlmanax[i]
+		res.Days = append(res.Days, AlmanaxXpDay{
+			Date:            m.Almanax.Date,
+			ExperienceRatio: m.Almanax.XpRatio,
+			OptimalLevel:    m.Almanax.OptimalLvl,
+			Duration:        m.Almanax.Duration,
+			XpByLevel:       experienceCurve(m),
+		})
+	}
+
+	if levelInt != nil {
+		res.Level = levelInt
+		res.BestDays = bestExperienceDays(res.Days, *levelInt, int(limit))
+	}
+
+	utils.RequestsTotal.Inc()
+
+	utils.WriteCacheHeader(&w)
+	if err = json.NewEncoder(w).Encode(res); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
@@ -0,0 +1,77 @@
+package almanax
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/database"
+)
+
+func TestExperienceCurve(t *testing.T) {
+	m := &database.MappedAlmanax{Almanax: database.Almanax{OptimalLvl: 100, XpRatio: 1, Duration: 1}}
+	curve := experienceCurve(m)
+
+	if len(curve) != maxCharacterLevel {
+		t.Fatalf("Expected %d levels, got %d", maxCharacterLevel, len(curve))
+	}
+	for level := 1; level <= maxCharacterLevel; level++ {
+		if expected := experienceReward(level, 100, 1, 1); curve[level-1] != expected {
+			t.Errorf("Level %d: expected %d, got %d", level, expected, curve[level-1])
+		}
+	}
+
+	// levels above the optimal one only get a reduced reward, capped at 1.5 times the optimal level
+	if curve[0] != 520 {
+		t.Error("Expected 520 for level 1, got ", curve[0])
+	}
+	if curve[149] != curve[199] {
+		t.Errorf("Expected the reward to stop growing at level 150, got %d and %d", curve[149], curve[199])
+	}
+	if curve[100] <= curve[99] {
+		t.Errorf("Expected the reward to grow above the optimal level, got %d and %d", curve[99], curve[100])
+	}
+}
+
+func TestBestExperienceDays(t *testing.T) {
+	day := func(date string, xp int) AlmanaxXpDay {
+		return AlmanaxXpDay{Date: date, XpByLevel: []int{0, xp}}
+	}
+	days := []AlmanaxXpDay{
+		day("2024-05-01", 100),
+		day("2024-05-02", 300),
+		day("2024-05-03", 200),
+		day("2024-05-04", 300),
+	}
+
+	tests := []struct {
+		limit    int
+		expected []AlmanaxXpBestDay
+	}{
+		{10, []AlmanaxXpBestDay{{"2024-05-02", 300}, {"2024-05-04", 300}, {"2024-05-03", 200}, {"2024-05-01", 100}}},
+		{2, []AlmanaxXpBestDay{{"2024-05-02", 300}, {"2024-05-04", 300}}},
+		{0, []AlmanaxXpBestDay{}},
+		{-1, []AlmanaxXpBestDay{}},
+	}
+
+	for _, test := range tests {
+		best := bestExperienceDays(days, 2, test.limit)
+		if !reflect.DeepEqual(best, test.expected) {
+			t.Errorf("Limit %d: expected %v, got %v", test.limit, test.expected, best)
+		}
+	}
+}
+
+func TestGetAlmanaxXpRejectsNegativeLimit(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/en/almanax/xp?level=50&limit=-1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "date", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)))
+	rec := httptest.NewRecorder()
+	GetAlmanaxXp(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a negative limit to be rejected, got %d", rec.Code)
+	}
+}
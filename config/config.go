@@ -1,6 +1,7 @@
 package config
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/dofusdude/ankabuffer"
@@ -8,32 +9,88 @@ import (
 )
 
 var (
-	Languages               = []string{"de", "en", "es", "fr", "pt"}
-	ItemImgResolutions      = []string{"64", "128"}
-	MountImgResolutions     = []string{"64", "256"}
-	ApiHostName             string
-	ApiPort                 string
-	ApiScheme               string
-	DockerMountDataPath     string
-	MajorVersion            int
+	Languages              = []string{"de", "en", "es", "fr", "pt"}
+	ItemImgResolutions     = []string{"64", "128"}
+	MountImgResolutions    = []string{"64", "256"}
+	ApiHostName            string
+	ApiPort                string
+	ApiScheme              string
+	DockerMountDataPath    string
+	MajorVersion           int
+	AlmanaxLocation        *time.Location
+	AlmanaxResetTime       time.Duration // since midnight in AlmanaxLocation
+	DbDir                  string
+	FileHashes             ankabuffer.Manifest // TODO why is this here?
+	MeiliHost              string
+	MeiliKey               string
+	MeiliReconnectInterval time.Duration
+	SearchDictionaryDir    string
+	SearchDictionaryReload time.Duration
+	PrometheusEnabled      bool
+	PublishFileServer      bool
+	PersistedElements      utils.PersistentStringKeysMap // TODO remove, since not a fixed config param
+	PersistedTypes         utils.PersistentStringKeysMap // TODO remove, since not a fixed config param
+	IsBeta                 bool
+	LastUpdate             time.Time // TODO remove, since not a fixed config param
+	ElementsUrl            string
+	TypesUrl               string
+	ReleaseUrl             string
+	UpdateHookToken        string
+	ReleasePollInterval    time.Duration // 0 disables polling for new releases
+	GithubToken            string
+	RateLimitEnabled       bool
+	RateLimitQuotas        map[string]RateLimitQuota // by endpoint class: search, all, single and default, auth_fail for unknown API keys
+	TrustProxyHeaders      bool
+	CorsOrigins            []string
+	CorsMaxAge             time.Duration
+	AdminCorsOrigins       []string
+	AdminCorsCredentials   bool
+	WebhookInterval        time.Duration
+	MigrateOnStart         bool
+	DofusVersion           string
+	ApiVersion             string
+	SkipAlmanax            bool
+)
+
+// currentVersion is the game version that is served right now. The update loop swaps it while handlers read it.
+var currentVersion atomic.Pointer[utils.GameVersion]
+
+// CurrentVersion returns the game version that is served right now.
+func CurrentVersion() utils.GameVersion {
+	if version := currentVersion.Load(); version != nil {
+		return *version
+	}
+	return utils.GameVersion{}
+}
+
+func SetCurrentVersion(version utils.GameVersion) {
+	currentVersion.Store(&version)
+}
+
+// Runtime holds the settings that a config reload can change while handlers read them.
+type Runtime struct {
 	AlmanaxMaxLookAhead     int
 	AlmanaxDefaultLookAhead int
-	DbDir                   string
-	FileHashes              ankabuffer.Manifest // TODO why is this here?
-	MeiliHost               string
-	MeiliKey                string
-	PrometheusEnabled       bool
-	PublishFileServer       bool
-	PersistedElements       utils.PersistentStringKeysMap // TODO remove, since not a fixed config param
-	PersistedTypes          utils.PersistentStringKeysMap // TODO remove, since not a fixed config param
-	IsBeta                  bool
-	LastUpdate              time.Time // TODO remove, since not a fixed config param
-	ElementsUrl             string
-	TypesUrl                string
-	ReleaseUrl              string
-	UpdateHookToken         string
-	DofusVersion            string
-	CurrentVersion          utils.GameVersion // TODO remove, since not a fixed config param
-	ApiVersion              string
-	SkipAlmanax             bool
-)
+	UpdateHookSecret        string
+	WebhookToken            string
+}
+
+var runtime atomic.Pointer[Runtime]
+
+// RuntimeConfig returns the reloadable settings that apply right now.
+func RuntimeConfig() Runtime {
+	if settings := runtime.Load(); settings != nil {
+		return *settings
+	}
+	return Runtime{}
+}
+
+func SetRuntimeConfig(settings Runtime) {
+	runtime.Store(&settings)
+}
+
+// RateLimitQuota allows Requests per Window, refilled continuously, with bursts of up to Requests.
+type RateLimitQuota struct {
+	Requests int
+	Window   time.Duration
+}
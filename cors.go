@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+)
+
+// CorsPolicy tells browsers which other origins may call a route group. An empty AllowedOrigins allows none.
+type CorsPolicy struct {
+	AllowedOrigins   []string // "*" allows every origin
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // how long browsers may cache a preflight answer
+}
+
+func (p *CorsPolicy) allowsOrigin(origin string) bool {
+	return slices.Contains(p.AllowedOrigins, "*") || slices.Contains(p.AllowedOrigins, origin)
+}
+
+// allowOriginValue is "*" for public policies. With credentials, browsers need the origin itself.
+func (p *CorsPolicy) allowOriginValue(origin string) string {
+	if slices.Contains(p.AllowedOrigins, "*") && !p.AllowCredentials {
+		return "*"
+	}
+	return origin
+}
+
+func (p *CorsPolicy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(p.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// cors applies a policy to a route group. Preflight requests are answered here and never reach the handlers, so
+// they also do not count against rate limits.
+func cors(policy CorsPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !policy.allowsOrigin(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", policy.allowOriginValue(origin))
+			if policy.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(policy.ExposedHeaders) != 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !slices.Contains(policy.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) ||
+				!policy.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+			if len(policy.AllowedHeaders) != 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+			}
+			if policy.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// publicCorsPolicy is for the read-only data routes, open to every origin unless CORS_ORIGINS says otherwise.
+func publicCorsPolicy() CorsPolicy {
+	return CorsPolicy{
+		AllowedOrigins: config.CorsOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodHead},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		ExposedHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "X-Request-Id"},
+		MaxAge:         config.CorsMaxAge,
+	}
+}
+
+// webhookCorsPolicy lets the same origins as the data routes manage webhooks with their bearer token.
+func webhookCorsPolicy() CorsPolicy {
+	return CorsPolicy{
+		AllowedOrigins: config.CorsOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAge:         config.CorsMaxAge,
+	}
+}
+
+// adminCorsPolicy only allows the origins of the admin tooling, none by default.
+func adminCorsPolicy() CorsPolicy {
+	return CorsPolicy{
+		AllowedOrigins:   config.AdminCorsOrigins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Admin-Key", "X-Admin-Timestamp", "X-Admin-Signature"},
+		AllowCredentials: config.AdminCorsCredentials,
+		MaxAge:           config.CorsMaxAge,
+	}
+}
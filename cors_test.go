@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCorsPreflight(t *testing.T) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true })
+	public := cors(CorsPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodGet}, AllowedHeaders: []string{"X-API-Key"}})(next)
+	admin := cors(CorsPolicy{
+		AllowedOrigins:   []string{"https://admin.example.com"},
+		AllowedMethods:   []string{http.MethodPost},
+		AllowedHeaders:   []string{"Authorization"},
+		AllowCredentials: true,
+	})(next)
+
+	preflight := func(handler http.Handler, origin string, method string, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", headers)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := preflight(public, "https://any.example.com", http.MethodGet, "x-api-key"); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected the public preflight to pass, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := preflight(public, "https://any.example.com", http.MethodDelete, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a disallowed method to be rejected, got %d", rec.Code)
+	}
+	if rec := preflight(admin, "https://any.example.com", http.MethodPost, "authorization"); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected a foreign origin to be rejected by the admin policy, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec := preflight(admin, "https://admin.example.com", http.MethodPost, "authorization")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://admin.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("Expected the admin origin to pass with credentials, got %d %v", rec.Code, rec.Header())
+	}
+
+	if reached {
+		t.Error("Expected preflight requests to never reach the handler")
+	}
+}
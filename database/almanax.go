@@ -3,68 +3,136 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"net/url"
 	"path"
+	"runtime"
 	"strings"
 	"sync"
 
-	"github.com/charmbracelet/log"
-	"github.com/dofusdude/dodumap"
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
 )
 
-var repositoryMutex = sync.Mutex{}
 var DatabaseName = "almanax.db"
 
+// Repository is the long-lived almanax database. SQLite only allows one writer at a time, so all writes go through
+// Db with a single connection while handlers read from a read-only pool that does not block on them thanks to WAL.
 type Repository struct {
-	Db  *sql.DB
-	ctx context.Context
+	Db     *sql.DB
+	readDb *sql.DB
+	ctx    context.Context
+
+	stmtMutex sync.Mutex
+	stmts     map[string]*sql.Stmt
 }
 
-func NewDatabaseRepository(ctx context.Context, workdir string) *Repository {
-	repo := Repository{}
-	repo.Init(ctx, workdir)
-	return &repo
+func sqliteDsn(dbpath string, params ...string) string {
+	return fmt.Sprintf("file:%s?%s", (&url.URL{Path: dbpath}).EscapedPath(), strings.Join(params, "&"))
 }
 
-func (r *Repository) Init(ctx context.Context, workdir string) error {
+// OpenRepository opens the almanax database in workdir and creates it if it does not exist yet. Close it on shutdown.
+func OpenRepository(ctx context.Context, workdir string) (*Repository, error) {
 	dbpath := path.Join(workdir, DatabaseName)
-	// check if the file exists
-	_, err := os.Stat(dbpath)
+
+	writeDb, err := sql.Open("sqlite3", sqliteDsn(dbpath, "_pragma=journal_mode(wal)", "_pragma=busy_timeout(5000)", "_pragma=foreign_keys(1)", "_txlock=immediate"))
 	if err != nil {
-		if os.IsNotExist(err) {
-			log.Info("Database does not exist, creating")
-			file, err := os.Create(dbpath)
-			if err != nil {
-				return err
-			}
-			file.Close()
-		} else {
-			return err
-		}
+		return nil, err
+	}
+	writeDb.SetMaxOpenConns(1)
+	writeDb.SetConnMaxIdleTime(0)
+
+	// the first connection creates the file and switches it to WAL before any reader opens it
+	if err = writeDb.PingContext(ctx); err != nil {
+		writeDb.Close()
+		return nil, fmt.Errorf("could not open %s: %w", dbpath, err)
+	}
+
+	readDb, err := sql.Open("sqlite3", sqliteDsn(dbpath, "mode=ro", "_pragma=busy_timeout(5000)", "_pragma=query_only(1)"))
+	if err != nil {
+		writeDb.Close()
+		return nil, err
 	}
+	readDb.SetMaxOpenConns(runtime.NumCPU())
+	readDb.SetMaxIdleConns(runtime.NumCPU())
+
+	return &Repository{
+		Db:     writeDb,
+		readDb: readDb,
+		ctx:    ctx,
+		stmts:  make(map[string]*sql.Stmt),
+	}, nil
+}
 
-	sqliteDatabase, err := sql.Open("sqlite3", dbpath)
+func (r *Repository) Close() error {
+	r.stmtMutex.Lock()
+	for _, stmt := range r.stmts {
+		stmt.Close()
+	}
+	r.stmts = make(map[string]*sql.Stmt)
+	r.stmtMutex.Unlock()
+
+	return errors.Join(r.readDb.Close(), r.Db.Close())
+}
+
+// CheckSchema fails if not all embedded migrations ran.
+func (r *Repository) CheckSchema() error {
+	available, err := AvailableMigrations()
 	if err != nil {
 		return err
 	}
-	r.Db = sqliteDatabase
-	r.ctx = ctx
+	latest := available[len(available)-1]
+
+	var version uint
+	var dirty bool
+	err = r.readDb.QueryRowContext(r.ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if err == sql.ErrNoRows || strings.Contains(err.Error(), "no such table") {
+			return fmt.Errorf("almanax database has no migrations, run `doduapi migrate up` first")
+		}
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("almanax database migration %d failed halfway, fix it before starting", version)
+	}
+
+	if version < latest {
+		return fmt.Errorf("almanax database is at migration %d but %d is needed, run `doduapi migrate up` first", version, latest)
+	}
 
 	return nil
 }
 
-func (r *Repository) Deinit() {
-	r.Db.Close()
-	r.Db = nil
+// readStmt returns the prepared statement of a read query, preparing it on the read pool on first use.
+func (r *Repository) readStmt(query string) (*sql.Stmt, error) {
+	r.stmtMutex.Lock()
+	defer r.stmtMutex.Unlock()
+
+	if stmt, ok := r.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := r.readDb.PrepareContext(r.ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	r.stmts[query] = stmt
+	return stmt, nil
 }
 
-func (r *Repository) GetAlmanaxByDateRangeAndNameID(from, to, nameID string) ([]MappedAlmanax, error) {
-	query := `
+func (r *Repository) query(query string, args ...any) (*sql.Rows, error) {
+	stmt, err := r.readStmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(r.ctx, args...)
+}
+
+const mappedAlmanaxSelect = `
 		SELECT
-			a.id, a.bonus_id, a.tribute_id, a.date, a.reward_kamas, a.experience_ratio, a.optimal_level, a.duration, a.created_at, a.updated_at, a.deleted_at,
+			a.id, a.bonus_id, a.tribute_id, a.date, a.reward_kamas, a.experience_ratio, a.optimal_level, a.duration, a.game_version, a.created_at, a.updated_at, a.deleted_at,
 			b.id, b.bonus_type_id, b.description_en, b.description_fr, b.description_es, b.description_de, b.description_pt,
 			bt.id, bt.name_id, bt.name_en, bt.name_fr, bt.name_es, bt.name_de, bt.name_pt,
 			t.id, t.item_name_en, t.item_name_fr, t.item_name_es, t.item_name_de, t.item_name_pt,
@@ -72,11 +140,11 @@ func (r *Repository) GetAlmanaxByDateRangeAndNameID(from, to, nameID string) ([]
 		FROM almanax AS a
 		JOIN bonus AS b ON a.bonus_id = b.id
 		JOIN bonus_types AS bt ON b.bonus_type_id = bt.id
-		JOIN tribute AS t ON a.tribute_id = t.id
-		WHERE a.date >= ? AND a.date <= ? AND bt.name_id = ? AND a.deleted_at IS NULL
-		ORDER BY a.date ASC`
+		JOIN tribute AS t ON a.tribute_id = t.id`
 
-	rows, err := r.Db.Query(query, from, to, nameID)
+// queryMappedAlmanax runs a query that starts with mappedAlmanaxSelect and scans the joined rows.
+func (r *Repository) queryMappedAlmanax(query string, args ...any) ([]MappedAlmanax, error) {
+	rows, err := r.query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +158,7 @@ func (r *Repository) GetAlmanaxByDateRangeAndNameID(from, to, nameID string) ([]
 
 		err := rows.Scan(
 			&denorm.Almanax.ID, &denorm.Almanax.BonusID, &denorm.Almanax.TributeID, &denorm.Almanax.Date,
-			&denorm.Almanax.RewardKamas, &denorm.Almanax.XpRatio, &denorm.Almanax.OptimalLvl, &denorm.Almanax.Duration, &denorm.Almanax.CreatedAt, &denorm.Almanax.UpdatedAt, &deletedAt,
+			&denorm.Almanax.RewardKamas, &denorm.Almanax.XpRatio, &denorm.Almanax.OptimalLvl, &denorm.Almanax.Duration, &denorm.Almanax.GameVersion, &denorm.Almanax.CreatedAt, &denorm.Almanax.UpdatedAt, &deletedAt,
 			&denorm.Bonus.ID, &denorm.Bonus.BonusTypeID, &denorm.Bonus.DescriptionEn, &denorm.Bonus.DescriptionFr,
 			&denorm.Bonus.DescriptionEs, &denorm.Bonus.DescriptionDe, &denorm.Bonus.DescriptionPt,
 			&denorm.BonusType.ID, &denorm.BonusType.NameID, &denorm.BonusType.NameEn, &denorm.BonusType.NameFr,
@@ -116,52 +184,65 @@ func (r *Repository) GetAlmanaxByDateRangeAndNameID(from, to, nameID string) ([]
 	return result, nil
 }
 
+func (r *Repository) GetAlmanaxByDateRangeAndNameID(from, to, nameID string) ([]MappedAlmanax, error) {
+	query := mappedAlmanaxSelect + `
+		WHERE a.date >= ? AND a.date <= ? AND bt.name_id = ? AND a.deleted_at IS NULL
+		ORDER BY a.date ASC`
+	return r.queryMappedAlmanax(query, from, to, nameID)
+}
+
 func (r *Repository) GetAlmanaxByDateRange(from, to string) ([]MappedAlmanax, error) {
+	query := mappedAlmanaxSelect + `
+		WHERE a.date >= ? AND a.date <= ? AND a.deleted_at IS NULL
+		ORDER BY a.date ASC`
+	return r.queryMappedAlmanax(query, from, to)
+}
+
+// GetAlmanaxHistory lists every assignment a day ever had, including replaced ones, oldest first.
+func (r *Repository) GetAlmanaxHistory(date string) ([]MappedAlmanax, error) {
+	query := mappedAlmanaxSelect + `
+		WHERE a.date = ?
+		ORDER BY a.created_at ASC, a.id ASC`
+	return r.queryMappedAlmanax(query, date)
+}
+
+// GetNextAlmanaxByItem lists the next days starting at from where the tribute is the given item.
+func (r *Repository) GetNextAlmanaxByItem(from string, itemAnkamaID int64, limit int) ([]MappedAlmanax, error) {
+	query := mappedAlmanaxSelect + `
+		WHERE a.date >= ? AND t.item_ankama_id = ? AND a.deleted_at IS NULL
+		ORDER BY a.date ASC
+		LIMIT ?`
+	return r.queryMappedAlmanax(query, from, itemAnkamaID, limit)
+}
+
+// TributeDate is a day where an item is asked for as tribute.
+type TributeDate struct {
+	Date     string
+	Quantity int
+}
+
+// GetUpcomingTributes maps every item that is a tribute on a stored day starting at from to those days, earliest first.
+func (r *Repository) GetUpcomingTributes(from string) (map[int64][]TributeDate, error) {
 	query := `
-		SELECT
-			a.id, a.bonus_id, a.tribute_id, a.date, a.reward_kamas, a.experience_ratio, a.optimal_level, a.duration, a.created_at, a.updated_at, a.deleted_at,
-			b.id, b.bonus_type_id, b.description_en, b.description_fr, b.description_es, b.description_de, b.description_pt,
-			bt.id, bt.name_id, bt.name_en, bt.name_fr, bt.name_es, bt.name_de, bt.name_pt,
-			t.id, t.item_name_en, t.item_name_fr, t.item_name_es, t.item_name_de, t.item_name_pt,
-			t.item_ankama_id, t.item_category_id, t.item_doduapi_uri, t.quantity
+		SELECT t.item_ankama_id, a.date, t.quantity
 		FROM almanax AS a
-		JOIN bonus AS b ON a.bonus_id = b.id
-		JOIN bonus_types AS bt ON b.bonus_type_id = bt.id
 		JOIN tribute AS t ON a.tribute_id = t.id
-		WHERE a.date >= ? AND a.date <= ? AND a.deleted_at IS NULL
+		WHERE a.date >= ? AND a.deleted_at IS NULL
 		ORDER BY a.date ASC`
-
-	rows, err := r.Db.Query(query, from, to)
+	rows, err := r.query(query, from)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var result []MappedAlmanax
-
+	result := make(map[int64][]TributeDate)
 	for rows.Next() {
-		var denorm MappedAlmanax
-		var deletedAt sql.NullTime
-
-		err := rows.Scan(
-			&denorm.Almanax.ID, &denorm.Almanax.BonusID, &denorm.Almanax.TributeID, &denorm.Almanax.Date,
-			&denorm.Almanax.RewardKamas, &denorm.Almanax.XpRatio, &denorm.Almanax.OptimalLvl, &denorm.Almanax.Duration, &denorm.Almanax.CreatedAt, &denorm.Almanax.UpdatedAt, &deletedAt,
-			&denorm.Bonus.ID, &denorm.Bonus.BonusTypeID, &denorm.Bonus.DescriptionEn, &denorm.Bonus.DescriptionFr,
-			&denorm.Bonus.DescriptionEs, &denorm.Bonus.DescriptionDe, &denorm.Bonus.DescriptionPt,
-			&denorm.BonusType.ID, &denorm.BonusType.NameID, &denorm.BonusType.NameEn, &denorm.BonusType.NameFr,
-			&denorm.BonusType.NameEs, &denorm.BonusType.NameDe, &denorm.BonusType.NamePt,
-			&denorm.Tribute.ID, &denorm.Tribute.ItemNameEn, &denorm.Tribute.ItemNameFr, &denorm.Tribute.ItemNameEs, &denorm.Tribute.ItemNameDe, &denorm.Tribute.ItemNamePt,
-			&denorm.Tribute.ItemAnkamaID, &denorm.Tribute.ItemCategoryId,
-			&denorm.Tribute.ItemDoduapiUri, &denorm.Tribute.Quantity)
-
-		if err != nil {
+		var itemAnkamaID int64
+		var tribute TributeDate
+		if err := rows.Scan(&itemAnkamaID, &tribute.Date, &tribute.Quantity); err != nil {
 			return nil, err
 		}
-		if deletedAt.Valid {
-			denorm.Almanax.DeletedAt = &deletedAt.Time
-		}
-
-		result = append(result, denorm)
+		result[itemAnkamaID] = append(result[itemAnkamaID], tribute)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -171,172 +252,97 @@ func (r *Repository) GetAlmanaxByDateRange(from, to string) ([]MappedAlmanax, er
 	return result, nil
 }
 
-func (r *Repository) CreateBonus(bonus *Bonus) (int64, error) {
-	query := `INSERT INTO bonus (bonus_type_id, description_en, description_fr, description_es, description_de, description_pt, created_at, updated_at)
-	          VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))`
-	result, err := r.Db.Exec(query, bonus.BonusTypeID, bonus.DescriptionEn, bonus.DescriptionFr, bonus.DescriptionEs,
-		bonus.DescriptionDe, bonus.DescriptionPt)
+// GetUpcomingTributesByItem lists the stored days starting at from where the item is the tribute, earliest first.
+func (r *Repository) GetUpcomingTributesByItem(from string, itemAnkamaID int64) ([]TributeDate, error) {
+	query := `
+		SELECT a.date, t.quantity
+		FROM almanax AS a
+		JOIN tribute AS t ON a.tribute_id = t.id
+		WHERE a.date >= ? AND t.item_ankama_id = ? AND a.deleted_at IS NULL
+		ORDER BY a.date ASC`
+	rows, err := r.query(query, from, itemAnkamaID)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return result.LastInsertId()
-}
+	result := make([]TributeDate, 0)
+	for rows.Next() {
+		var tribute TributeDate
+		if err := rows.Scan(&tribute.Date, &tribute.Quantity); err != nil {
+			return nil, err
+		}
+		result = append(result, tribute)
+	}
 
-func (r *Repository) CreateTribute(tribute *Tribute) (int64, error) {
-	query := `INSERT INTO tribute (item_name_en, item_name_fr, item_name_es, item_name_de, item_name_pt, item_ankama_id, item_category_id, item_doduapi_uri, quantity, created_at, updated_at)
-	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))`
-	result, err := r.Db.Exec(query, tribute.ItemNameEn, tribute.ItemNameFr, tribute.ItemNameEs, tribute.ItemNameDe,
-		tribute.ItemNamePt, tribute.ItemAnkamaID, tribute.ItemCategoryId, tribute.ItemDoduapiUri, tribute.Quantity)
-	if err != nil {
-		return 0, err
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return result.LastInsertId()
+	return result, nil
 }
 
-func (r *Repository) CreateOrUpdate(date string, almanax *dodumap.MappedMultilangNPCAlmanaxUnity) (int64, error) {
-	bonusType := enNameToId(almanax.BonusType["en"])
-	query := `SELECT id FROM bonus_types WHERE name_id = ? AND deleted_at IS NULL`
-	var bonusTypeID int64
-	err := r.Db.QueryRow(query, bonusType).Scan(&bonusTypeID)
-	if err == sql.ErrNoRows {
-		bonusTypeID, err = r.CreateBonusType(&BonusType{
-			NameID: bonusType,
-			NameEn: almanax.BonusType["en"],
-			NameFr: almanax.BonusType["fr"],
-			NameEs: almanax.BonusType["es"],
-			NameDe: almanax.BonusType["de"],
-			NamePt: almanax.BonusType["pt"],
-		})
-		if err != nil {
-			return -1, err
-		}
-	}
-
-	query = `SELECT id FROM bonus WHERE description_en = ? AND deleted_at IS NULL`
-	var bonusID int64
-	err = r.Db.QueryRow(query, almanax.Bonus["en"]).Scan(&bonusID)
-	if err == sql.ErrNoRows {
-		bonusID, err = r.CreateBonus(&Bonus{
-			BonusTypeID:   bonusTypeID,
-			DescriptionEn: almanax.Bonus["en"],
-			DescriptionFr: almanax.Bonus["fr"],
-			DescriptionEs: almanax.Bonus["es"],
-			DescriptionDe: almanax.Bonus["de"],
-			DescriptionPt: almanax.Bonus["pt"],
-		})
-		if err != nil {
-			return -1, err
-		}
-	}
+// GetNextAlmanaxByNameID lists the next days starting at from with the given bonus type.
+func (r *Repository) GetNextAlmanaxByNameID(from string, nameID string, limit int) ([]MappedAlmanax, error) {
+	query := mappedAlmanaxSelect + `
+		WHERE a.date >= ? AND bt.name_id = ? AND a.deleted_at IS NULL
+		ORDER BY a.date ASC
+		LIMIT ?`
+	return r.queryMappedAlmanax(query, from, nameID, limit)
+}
 
-	query = `SELECT id FROM tribute WHERE item_ankama_id = ? AND quantity = ? AND deleted_at IS NULL`
-	var tributeID int64
-	err = r.Db.QueryRow(query, almanax.Offering.ItemId, almanax.Offering.Quantity).Scan(&tributeID)
-	if err == sql.ErrNoRows {
-		var itemApiUri string
-		game := "dofus3"
-		version := "v1"
-		switch almanax.Offering.ItemCategoryId {
-		case 1: // consumables
-			itemApiUri = fmt.Sprintf("%s/%s/${lang}/items/consumables/%d", game, version, almanax.Offering.ItemId)
-		case 2: // resources
-			itemApiUri = fmt.Sprintf("%s/%s/${lang}/items/resources/%d", game, version, almanax.Offering.ItemId)
-		case 0: // equipment
-			itemApiUri = fmt.Sprintf("%s/%s/${lang}/items/equipment/%d", game, version, almanax.Offering.ItemId)
-		case 3: // quest
-			itemApiUri = fmt.Sprintf("%s/%s/${lang}/items/quest/%d", game, version, almanax.Offering.ItemId)
-		case 5: // cosmetics
-			itemApiUri = fmt.Sprintf("%s/%s/${lang}/items/cosmetics/%d", game, version, almanax.Offering.ItemId)
-		}
+// AlmanaxMonthCount is the number of days in a month (YYYY-MM) matching a reverse lookup.
+type AlmanaxMonthCount struct {
+	Month string
+	Count int
+}
 
-		tributeID, err = r.CreateTribute(&Tribute{
-			ItemNameEn:     almanax.Offering.ItemName["en"],
-			ItemNameFr:     almanax.Offering.ItemName["fr"],
-			ItemNameEs:     almanax.Offering.ItemName["es"],
-			ItemNameDe:     almanax.Offering.ItemName["de"],
-			ItemNamePt:     almanax.Offering.ItemName["pt"],
-			ItemAnkamaID:   int64(almanax.Offering.ItemId),
-			ItemCategoryId: almanax.Offering.ItemCategoryId,
-			ItemDoduapiUri: itemApiUri,
-			Quantity:       almanax.Offering.Quantity,
-		})
-		if err != nil {
-			return -1, err
-		}
+func (r *Repository) queryMonthCounts(query string, args ...any) ([]AlmanaxMonthCount, error) {
+	rows, err := r.query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	query = `SELECT id FROM almanax WHERE date = ? AND deleted_at IS NULL LIMIT 1`
-	var id int64
-	err = r.Db.QueryRow(query, date).Scan(&id)
-	if err == sql.ErrNoRows {
-		query = `
-			INSERT INTO almanax (bonus_id, tribute_id, date, reward_kamas, experience_ratio, optimal_level, duration, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))`
-		result, err := r.Db.Exec(query, bonusID, tributeID, date, almanax.RewardKamas, almanax.ExperienceRatio, almanax.OptimalLevel, almanax.Duration)
-		if err != nil {
-			return 0, err
-		}
-		return result.LastInsertId()
-	} else {
-		query = `SELECT bonus_id, tribute_id, reward_kamas, experience_ratio, optimal_level, duration FROM almanax WHERE id = ? AND deleted_at IS NULL`
-		var exBonusID, exTributeID int64
-		var exRewardKamas, optimal_level int
-		var xp_ratio, duration float64
-		err = r.Db.QueryRow(query, id).Scan(&exBonusID, &exTributeID, &exRewardKamas, &xp_ratio, &optimal_level, &duration)
-		if err != nil {
-			return 0, err
+	result := make([]AlmanaxMonthCount, 0)
+	for rows.Next() {
+		var count AlmanaxMonthCount
+		if err := rows.Scan(&count.Month, &count.Count); err != nil {
+			return nil, err
 		}
+		result = append(result, count)
+	}
 
-		if exBonusID != bonusID || exTributeID != tributeID || exRewardKamas != almanax.RewardKamas || xp_ratio != almanax.ExperienceRatio || optimal_level != almanax.OptimalLevel || duration != almanax.Duration {
-			err = r.UpdateAlmanax(&Almanax{
-				ID:          id,
-				BonusID:     bonusID,
-				TributeID:   tributeID,
-				Date:        date,
-				RewardKamas: int64(almanax.RewardKamas),
-				OptimalLvl:  almanax.OptimalLevel,
-				Duration:    almanax.Duration,
-				XpRatio:     almanax.ExperienceRatio,
-			})
-			if err != nil {
-				return 0, err
-			}
-		}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return id, nil
+	return result, nil
 }
 
-func (r *Repository) UpdateAlmanax(almanax *Almanax) error {
+// GetItemFrequency counts per month how often the item is the tribute between from and to.
+func (r *Repository) GetItemFrequency(from, to string, itemAnkamaID int64) ([]AlmanaxMonthCount, error) {
 	query := `
-		UPDATE almanax
-		SET bonus_id = ?, tribute_id = ?, date = ?, reward_kamas = ?, experience_ratio = ?, optimal_level = ?, duration = ?, updated_at = datetime('now')
-		WHERE id = ?`
-	_, err := r.Db.Exec(query, almanax.BonusID, almanax.TributeID, almanax.Date, almanax.RewardKamas, almanax.XpRatio, almanax.OptimalLvl, almanax.Duration, almanax.ID)
-	return err
-}
-
-func (r *Repository) UpdateFuture(data map[string]dodumap.MappedMultilangNPCAlmanaxUnity) error {
-	for date, almanax := range data {
-		_, err := r.CreateOrUpdate(date, &almanax)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+		SELECT substr(a.date, 1, 7) AS month, count(*)
+		FROM almanax AS a
+		JOIN tribute AS t ON a.tribute_id = t.id
+		WHERE a.date >= ? AND a.date <= ? AND t.item_ankama_id = ? AND a.deleted_at IS NULL
+		GROUP BY month
+		ORDER BY month ASC`
+	return r.queryMonthCounts(query, from, to, itemAnkamaID)
 }
 
-func (r *Repository) CreateBonusType(bonusType *BonusType) (int64, error) {
-	query := `INSERT INTO bonus_types (name_id, name_en, name_fr, name_es, name_de, name_pt, created_at, updated_at)
-	          VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))`
-	result, err := r.Db.Exec(query, bonusType.NameID, bonusType.NameEn, bonusType.NameFr, bonusType.NameEs,
-		bonusType.NameDe, bonusType.NamePt)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
+// GetBonusTypeFrequency counts per month how often the bonus type occurs between from and to.
+func (r *Repository) GetBonusTypeFrequency(from, to string, nameID string) ([]AlmanaxMonthCount, error) {
+	query := `
+		SELECT substr(a.date, 1, 7) AS month, count(*)
+		FROM almanax AS a
+		JOIN bonus AS b ON a.bonus_id = b.id
+		JOIN bonus_types AS bt ON b.bonus_type_id = bt.id
+		WHERE a.date >= ? AND a.date <= ? AND bt.name_id = ? AND a.deleted_at IS NULL
+		GROUP BY month
+		ORDER BY month ASC`
+	return r.queryMonthCounts(query, from, to, nameID)
 }
 
 func enNameToId(enName string) string {
@@ -346,7 +352,7 @@ func enNameToId(enName string) string {
 func (r *Repository) GetBonusTypes() ([]BonusType, error) {
 	query := `SELECT id, name_id, name_en, name_fr, name_es, name_de, name_pt
 	          FROM bonus_types WHERE deleted_at IS NULL`
-	rows, err := r.Db.Query(query)
+	rows, err := r.query(query)
 	if err != nil {
 		return nil, err
 	}
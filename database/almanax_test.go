@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dofusdude/dodumap"
+)
+
+func testRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	repo, err := OpenRepository(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	if _, err = repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func testAlmanax(bonusType string, itemId int, quantity int) dodumap.MappedMultilangNPCAlmanaxUnity {
+	almanax := dodumap.MappedMultilangNPCAlmanaxUnity{
+		Bonus:       map[string]string{"en": bonusType + " bonus.", "fr": "Bonus " + bonusType + "."},
+		BonusType:   map[string]string{"en": bonusType, "fr": bonusType},
+		RewardKamas: 1000,
+	}
+	almanax.Offering.ItemId = itemId
+	almanax.Offering.ItemName = map[string]string{"en": "Item", "fr": "Objet"}
+	almanax.Offering.Quantity = quantity
+	return almanax
+}
+
+func almanaxDates(almanax []MappedAlmanax) []string {
+	dates := make([]string, 0, len(almanax))
+	for _, m := range almanax {
+		dates = append(dates, m.Almanax.Date)
+	}
+	return dates
+}
+
+func TestGetNextAlmanaxByItem(t *testing.T) {
+	repo := testRepository(t)
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-04-30": testAlmanax("Loot", 289, 3),
+		"2024-05-01": testAlmanax("Experience", 289, 5),
+		"2024-05-15": testAlmanax("Loot", 303, 1),
+		"2024-06-02": testAlmanax("Loot", 289, 3),
+		"2024-07-10": testAlmanax("Harvest", 289, 2),
+	}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from     string
+		item     int64
+		limit    int
+		expected []string
+	}{
+		{"2024-05-01", 289, 8, []string{"2024-05-01", "2024-06-02", "2024-07-10"}},
+		{"2024-05-01", 289, 2, []string{"2024-05-01", "2024-06-02"}},
+		{"2024-05-01", 303, 8, []string{"2024-05-15"}},
+		{"2024-05-16", 303, 8, []string{}},
+		{"2024-05-01", 999, 8, []string{}},
+	}
+	for _, test := range tests {
+		found, err := repo.GetNextAlmanaxByItem(test.from, test.item, test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dates := almanaxDates(found); !reflect.DeepEqual(dates, test.expected) {
+			t.Errorf("Item %d from %s limit %d: expected %v, got %v", test.item, test.from, test.limit, test.expected, dates)
+		}
+	}
+}
+
+func TestAlmanaxFrequency(t *testing.T) {
+	repo := testRepository(t)
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-04-30": testAlmanax("Loot", 289, 3),
+		"2024-05-01": testAlmanax("Loot", 289, 5),
+		"2024-05-20": testAlmanax("Loot", 303, 1),
+		"2024-06-02": testAlmanax("Experience", 289, 3),
+		"2025-05-01": testAlmanax("Loot", 289, 3),
+	}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	byItem, err := repo.GetItemFrequency("2024-05-01", "2025-04-30", 289)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []AlmanaxMonthCount{{Month: "2024-05", Count: 1}, {Month: "2024-06", Count: 1}}
+	if !reflect.DeepEqual(byItem, expected) {
+		t.Errorf("Expected %v by item, got %v", expected, byItem)
+	}
+
+	byBonusType, err := repo.GetBonusTypeFrequency("2024-05-01", "2025-05-01", "loot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []AlmanaxMonthCount{{Month: "2024-05", Count: 2}, {Month: "2025-05", Count: 1}}
+	if !reflect.DeepEqual(byBonusType, expected) {
+		t.Errorf("Expected %v by bonus type, got %v", expected, byBonusType)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	repo, err := OpenRepository(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	if err = repo.CheckSchema(); err == nil || !strings.Contains(err.Error(), "no migrations") {
+		t.Error("Expected an unmigrated database to fail, got ", err)
+	}
+
+	m, err := repo.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Migrate(2); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.CheckSchema(); err == nil || !strings.Contains(err.Error(), "is at migration 2") {
+		t.Error("Expected an outdated database to fail, got ", err)
+	}
+
+	if _, err = repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.CheckSchema(); err != nil {
+		t.Error("Expected a migrated database to pass, got ", err)
+	}
+
+	if _, err = repo.Db.Exec(`UPDATE schema_migrations SET dirty = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.CheckSchema(); err == nil || !strings.Contains(err.Error(), "failed halfway") {
+		t.Error("Expected a dirty database to fail, got ", err)
+	}
+}
+
+func TestGetUpcomingTributesByItem(t *testing.T) {
+	repo := testRepository(t)
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-04-30": testAlmanax("Loot", 289, 3),
+		"2024-05-01": testAlmanax("Experience", 289, 5),
+		"2024-05-15": testAlmanax("Loot", 303, 1),
+		"2024-06-02": testAlmanax("Loot", 289, 3),
+	}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// a replaced day is not upcoming anymore
+	replaced := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{"2024-06-02": testAlmanax("Loot", 303, 2)}
+	if _, err := repo.UpdateFuture(replaced, "3.0.1", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tributes, err := repo.GetUpcomingTributesByItem("2024-05-01", 289)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []TributeDate{{Date: "2024-05-01", Quantity: 5}}; !reflect.DeepEqual(tributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, tributes)
+	}
+
+	tributes, err = repo.GetUpcomingTributesByItem("2024-05-01", 303)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []TributeDate{{Date: "2024-05-15", Quantity: 1}, {Date: "2024-06-02", Quantity: 2}}; !reflect.DeepEqual(tributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, tributes)
+	}
+}
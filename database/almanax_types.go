@@ -53,6 +53,7 @@ type Almanax struct {
 	XpRatio     float64    `db:"xp_ratio"`
 	OptimalLvl  int        `db:"optimal_lvl"`
 	Duration    float64    `db:"duration"`
+	GameVersion string     `db:"game_version"` // release that introduced this assignment of the day
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at"`
 	DeletedAt   *time.Time `db:"deleted_at"`
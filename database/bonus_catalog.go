@@ -0,0 +1,107 @@
+package database
+
+// BonusVariant is a description of a bonus type with the number of stored days that use it.
+type BonusVariant struct {
+	Bonus
+	Count int
+}
+
+// TributeCount is a tribute item with the number of stored days it was asked for with a bonus type.
+type TributeCount struct {
+	ItemAnkamaID   int64
+	ItemCategoryId int
+	ItemNameEn     string
+	ItemNameFr     string
+	ItemNameEs     string
+	ItemNameDe     string
+	ItemNamePt     string
+	Count          int
+}
+
+// GetBonusType returns sql.ErrNoRows if there is no bonus type with this name_id.
+func (r *Repository) GetBonusType(nameID string) (BonusType, error) {
+	query := `SELECT id, name_id, name_en, name_fr, name_es, name_de, name_pt
+	          FROM bonus_types WHERE name_id = ? AND deleted_at IS NULL`
+	var bonusType BonusType
+	err := r.readDb.QueryRowContext(r.ctx, query, nameID).Scan(&bonusType.ID, &bonusType.NameID, &bonusType.NameEn,
+		&bonusType.NameFr, &bonusType.NameEs, &bonusType.NameDe, &bonusType.NamePt)
+	return bonusType, err
+}
+
+// GetAlmanaxWindow returns the first and last stored date of the current almanax days.
+func (r *Repository) GetAlmanaxWindow() (string, string, error) {
+	query := `SELECT coalesce(min(date), ''), coalesce(max(date), '') FROM almanax WHERE deleted_at IS NULL`
+	var from, to string
+	err := r.readDb.QueryRowContext(r.ctx, query).Scan(&from, &to)
+	return from, to, err
+}
+
+// GetBonusVariants lists the descriptions of a bonus type, the most used first.
+func (r *Repository) GetBonusVariants(nameID string) ([]BonusVariant, error) {
+	query := `
+		SELECT b.id, b.bonus_type_id, b.description_en, b.description_fr, b.description_es, b.description_de, b.description_pt, count(a.id) AS uses
+		FROM bonus AS b
+		JOIN bonus_types AS bt ON b.bonus_type_id = bt.id
+		LEFT JOIN almanax AS a ON a.bonus_id = b.id AND a.deleted_at IS NULL
+		WHERE bt.name_id = ? AND b.deleted_at IS NULL
+		GROUP BY b.id
+		ORDER BY uses DESC, b.id ASC`
+	rows, err := r.query(query, nameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]BonusVariant, 0)
+	for rows.Next() {
+		var variant BonusVariant
+		err := rows.Scan(&variant.ID, &variant.BonusTypeID, &variant.DescriptionEn, &variant.DescriptionFr,
+			&variant.DescriptionEs, &variant.DescriptionDe, &variant.DescriptionPt, &variant.Count)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetTopTributesByNameID lists the tribute items that come up most often with a bonus type.
+func (r *Repository) GetTopTributesByNameID(nameID string, limit int) ([]TributeCount, error) {
+	query := `
+		SELECT t.item_ankama_id, t.item_category_id, t.item_name_en, t.item_name_fr, t.item_name_es, t.item_name_de, t.item_name_pt, count(*) AS uses
+		FROM almanax AS a
+		JOIN bonus AS b ON a.bonus_id = b.id
+		JOIN bonus_types AS bt ON b.bonus_type_id = bt.id
+		JOIN tribute AS t ON a.tribute_id = t.id
+		WHERE bt.name_id = ? AND a.deleted_at IS NULL
+		GROUP BY t.item_ankama_id
+		ORDER BY uses DESC, t.item_ankama_id ASC
+		LIMIT ?`
+	rows, err := r.query(query, nameID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]TributeCount, 0)
+	for rows.Next() {
+		var tribute TributeCount
+		err := rows.Scan(&tribute.ItemAnkamaID, &tribute.ItemCategoryId, &tribute.ItemNameEn, &tribute.ItemNameFr,
+			&tribute.ItemNameEs, &tribute.ItemNameDe, &tribute.ItemNamePt, &tribute.Count)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tribute)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
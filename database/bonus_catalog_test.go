@@ -0,0 +1,98 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dofusdude/dodumap"
+)
+
+func testCatalog(t *testing.T) *Repository {
+	t.Helper()
+
+	repo := testRepository(t)
+
+	rareLoot := testAlmanax("Loot", 303, 1)
+	rareLoot.Bonus = map[string]string{"en": "Rare loot bonus.", "fr": "Bonus de butin rare."}
+
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-05-01": testAlmanax("Loot", 289, 3),
+		"2024-05-02": rareLoot,
+		"2024-05-03": testAlmanax("Loot", 289, 5),
+		"2024-05-04": testAlmanax("Loot", 421, 2),
+		"2024-05-05": testAlmanax("Loot", 303, 1),
+		"2024-05-06": testAlmanax("Experience", 289, 3),
+	}
+	if _, err := repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// replaced days do not count anymore
+	replaced := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{"2024-05-06": testAlmanax("Loot", 421, 2)}
+	if _, err := repo.UpdateFuture(replaced, "3.0.1", false); err != nil {
+		t.Fatal(err)
+	}
+	replaced["2024-05-06"] = testAlmanax("Experience", 289, 3)
+	if _, err := repo.UpdateFuture(replaced, "3.0.2", false); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func TestGetBonusVariants(t *testing.T) {
+	repo := testCatalog(t)
+
+	variants, err := repo.GetBonusVariants("loot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	descriptions := make([]string, 0, len(variants))
+	counts := make([]int, 0, len(variants))
+	for _, variant := range variants {
+		descriptions = append(descriptions, variant.DescriptionEn)
+		counts = append(counts, variant.Count)
+	}
+	if !reflect.DeepEqual(descriptions, []string{"Loot bonus.", "Rare loot bonus."}) || !reflect.DeepEqual(counts, []int{4, 1}) {
+		t.Errorf("Expected the most used variant first, got %v with %v uses", descriptions, counts)
+	}
+
+	variants, err = repo.GetBonusVariants("unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 0 {
+		t.Error("Expected no variants for an unknown bonus type, got ", len(variants))
+	}
+}
+
+func TestGetTopTributesByNameID(t *testing.T) {
+	repo := testCatalog(t)
+
+	tests := []struct {
+		limit int
+		items []int64
+		uses  []int
+	}{
+		{10, []int64{289, 303, 421}, []int{2, 2, 1}},
+		{1, []int64{289}, []int{2}},
+	}
+
+	for _, test := range tests {
+		tributes, err := repo.GetTopTributesByNameID("loot", test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		items := make([]int64, 0, len(tributes))
+		uses := make([]int, 0, len(tributes))
+		for _, tribute := range tributes {
+			items = append(items, tribute.ItemAnkamaID)
+			uses = append(uses, tribute.Count)
+		}
+		if !reflect.DeepEqual(items, test.items) || !reflect.DeepEqual(uses, test.uses) {
+			t.Errorf("Limit %d: expected items %v with %v uses, got %v with %v", test.limit, test.items, test.uses, items, uses)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package database
 
 import (
+	"errors"
+	"sync/atomic"
+
 	"github.com/hashicorp/go-memdb"
 	"github.com/meilisearch/meilisearch-go"
 )
@@ -14,9 +17,23 @@ type SearchIndexes struct {
 var Db *memdb.MemDB
 var Indexes map[string]SearchIndexes
 
+// SearchDegraded is set while the search engine is unreachable or its indexes are not built. Search endpoints then
+// answer from the in-memory database instead.
+var SearchDegraded atomic.Bool
+
 type VersionT struct {
 	Search bool
 	MemDb  bool
 }
 
 var Version VersionT
+
+// SearchEngineUnreachable reports whether err comes from a request that never reached the search engine, as opposed
+// to a search the engine rejected.
+func SearchEngineUnreachable(err error) bool {
+	var meiliErr *meilisearch.Error
+	if errors.As(err, &meiliErr) {
+		return meiliErr.ErrCode == meilisearch.MeilisearchCommunicationError || meiliErr.ErrCode == meilisearch.MeilisearchMaxRetriesExceeded
+	}
+	return false
+}
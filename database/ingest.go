@@ -0,0 +1,326 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dofusdude/dodumap"
+)
+
+type IngestAction string
+
+const (
+	IngestInserted  IngestAction = "inserted"
+	IngestUpdated   IngestAction = "updated"
+	IngestUnchanged IngestAction = "unchanged"
+)
+
+type IngestChange struct {
+	Date   string
+	Action IngestAction
+}
+
+// IngestSummary counts what an ingestion did per day. A day that got another bonus or tribute counts as updated, its
+// old assignment stays in the history.
+type IngestSummary struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Changes   []IngestChange // only inserted and updated days, sorted by date
+}
+
+func (s *IngestSummary) add(date string, action IngestAction) {
+	switch action {
+	case IngestInserted:
+		s.Inserted++
+	case IngestUpdated:
+		s.Updated++
+	case IngestUnchanged:
+		s.Unchanged++
+		return
+	}
+	s.Changes = append(s.Changes, IngestChange{Date: date, Action: action})
+}
+
+// UpdateFuture stores the almanax days as seen in gameVersion in a single transaction, so a failure leaves the
+// database as it was. Running it again with the same data changes nothing. With dryRun, the transaction is rolled
+// back and the summary tells what would have changed.
+func (r *Repository) UpdateFuture(data map[string]dodumap.MappedMultilangNPCAlmanaxUnity, gameVersion string, dryRun bool) (IngestSummary, error) {
+	summary := IngestSummary{Changes: make([]IngestChange, 0)}
+
+	dates := make([]string, 0, len(data))
+	for date := range data {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	tx, err := r.Db.BeginTx(r.ctx, nil)
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback()
+
+	for _, date := range dates {
+		almanax := data[date]
+		action, err := upsertAlmanaxDay(tx, date, &almanax, gameVersion)
+		if err != nil {
+			return summary, fmt.Errorf("could not store almanax of %s: %w", date, err)
+		}
+		summary.add(date, action)
+	}
+
+	if dryRun {
+		return summary, nil
+	}
+
+	return summary, tx.Commit()
+}
+
+func upsertBonusType(tx *sql.Tx, almanax *dodumap.MappedMultilangNPCAlmanaxUnity) (int64, error) {
+	query := `
+		INSERT INTO bonus_types (name_id, name_en, name_fr, name_es, name_de, name_pt, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		ON CONFLICT (name_id) DO UPDATE SET
+			name_en = excluded.name_en, name_fr = excluded.name_fr, name_es = excluded.name_es,
+			name_de = excluded.name_de, name_pt = excluded.name_pt, deleted_at = NULL
+		RETURNING id`
+	var id int64
+	err := tx.QueryRow(query, enNameToId(almanax.BonusType["en"]), almanax.BonusType["en"], almanax.BonusType["fr"],
+		almanax.BonusType["es"], almanax.BonusType["de"], almanax.BonusType["pt"]).Scan(&id)
+	return id, err
+}
+
+func upsertBonus(tx *sql.Tx, bonusTypeID int64, almanax *dodumap.MappedMultilangNPCAlmanaxUnity) (int64, error) {
+	query := `
+		INSERT INTO bonus (bonus_type_id, description_en, description_fr, description_es, description_de, description_pt, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		ON CONFLICT (bonus_type_id, description_en) WHERE deleted_at IS NULL DO UPDATE SET
+			description_fr = excluded.description_fr, description_es = excluded.description_es,
+			description_de = excluded.description_de, description_pt = excluded.description_pt
+		RETURNING id`
+	var id int64
+	err := tx.QueryRow(query, bonusTypeID, almanax.Bonus["en"], almanax.Bonus["fr"], almanax.Bonus["es"],
+		almanax.Bonus["de"], almanax.Bonus["pt"]).Scan(&id)
+	return id, err
+}
+
+func tributeApiUri(almanax *dodumap.MappedMultilangNPCAlmanaxUnity) string {
+	game := "dofus3"
+	version := "v1"
+	switch almanax.Offering.ItemCategoryId {
+	case 1: // consumables
+		return fmt.Sprintf("%s/%s/${lang}/items/consumables/%d", game, version, almanax.Offering.ItemId)
+	case 2: // resources
+		return fmt.Sprintf("%s/%s/${lang}/items/resources/%d", game, version, almanax.Offering.ItemId)
+	case 0: // equipment
+		return fmt.Sprintf("%s/%s/${lang}/items/equipment/%d", game, version, almanax.Offering.ItemId)
+	case 3: // quest
+		return fmt.Sprintf("%s/%s/${lang}/items/quest/%d", game, version, almanax.Offering.ItemId)
+	case 5: // cosmetics
+		return fmt.Sprintf("%s/%s/${lang}/items/cosmetics/%d", game, version, almanax.Offering.ItemId)
+	}
+	return ""
+}
+
+func upsertTribute(tx *sql.Tx, almanax *dodumap.MappedMultilangNPCAlmanaxUnity) (int64, error) {
+	query := `
+		INSERT INTO tribute (item_name_en, item_name_fr, item_name_es, item_name_de, item_name_pt, item_ankama_id, item_category_id, item_doduapi_uri, quantity, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		ON CONFLICT (item_ankama_id, quantity) WHERE deleted_at IS NULL DO UPDATE SET
+			item_name_en = excluded.item_name_en, item_name_fr = excluded.item_name_fr, item_name_es = excluded.item_name_es,
+			item_name_de = excluded.item_name_de, item_name_pt = excluded.item_name_pt,
+			item_category_id = excluded.item_category_id, item_doduapi_uri = excluded.item_doduapi_uri
+		RETURNING id`
+	var id int64
+	err := tx.QueryRow(query, almanax.Offering.ItemName["en"], almanax.Offering.ItemName["fr"], almanax.Offering.ItemName["es"],
+		almanax.Offering.ItemName["de"], almanax.Offering.ItemName["pt"], almanax.Offering.ItemId,
+		almanax.Offering.ItemCategoryId, tributeApiUri(almanax), almanax.Offering.Quantity).Scan(&id)
+	return id, err
+}
+
+// upsertAlmanaxDay stores a day keyed by its date. If the day exists with another bonus or tribute, the old
+// assignment is soft-deleted and kept as history. Other changes are updated in place.
+func upsertAlmanaxDay(tx *sql.Tx, date string, almanax *dodumap.MappedMultilangNPCAlmanaxUnity, gameVersion string) (IngestAction, error) {
+	bonusTypeID, err := upsertBonusType(tx, almanax)
+	if err != nil {
+		return "", err
+	}
+
+	bonusID, err := upsertBonus(tx, bonusTypeID, almanax)
+	if err != nil {
+		return "", err
+	}
+
+	tributeID, err := upsertTribute(tx, almanax)
+	if err != nil {
+		return "", err
+	}
+
+	query := `SELECT id, bonus_id, tribute_id, reward_kamas, experience_ratio, optimal_level, duration FROM almanax WHERE date = ? AND deleted_at IS NULL`
+	var existing Almanax
+	err = tx.QueryRow(query, date).Scan(&existing.ID, &existing.BonusID, &existing.TributeID, &existing.RewardKamas,
+		&existing.XpRatio, &existing.OptimalLvl, &existing.Duration)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	action := IngestInserted
+	if err == nil {
+		if existing.BonusID == bonusID && existing.TributeID == tributeID {
+			if existing.RewardKamas == int64(almanax.RewardKamas) && existing.XpRatio == almanax.ExperienceRatio &&
+				existing.OptimalLvl == almanax.OptimalLevel && existing.Duration == almanax.Duration {
+				return IngestUnchanged, nil
+			}
+
+			query = `
+				UPDATE almanax
+				SET reward_kamas = ?, experience_ratio = ?, optimal_level = ?, duration = ?, updated_at = datetime('now')
+				WHERE id = ?`
+			_, err = tx.Exec(query, almanax.RewardKamas, almanax.ExperienceRatio, almanax.OptimalLevel, almanax.Duration, existing.ID)
+			return IngestUpdated, err
+		}
+
+		query = `UPDATE almanax SET deleted_at = datetime('now'), updated_at = datetime('now') WHERE id = ?`
+		if _, err = tx.Exec(query, existing.ID); err != nil {
+			return "", err
+		}
+		action = IngestUpdated
+	}
+
+	query = `
+		INSERT INTO almanax (bonus_id, tribute_id, date, reward_kamas, experience_ratio, optimal_level, duration, game_version, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))`
+	_, err = tx.Exec(query, bonusID, tributeID, date, almanax.RewardKamas, almanax.ExperienceRatio, almanax.OptimalLevel, almanax.Duration, gameVersion)
+	return action, err
+}
+
+// sqliteTime formats t like datetime('now'), so stored timestamps stay comparable.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// BackfillAlmanax fits the days of an older release into the history by its release date, in a single transaction.
+// Unlike UpdateFuture, it never replaces an assignment that came after the release: the days are stored as history
+// rows that were replaced by the next newer assignment, or become current only when nothing newer is stored. So the
+// releases can be backfilled in any order and more than once.
+func (r *Repository) BackfillAlmanax(data map[string]dodumap.MappedMultilangNPCAlmanaxUnity, gameVersion string, releasedAt time.Time) (IngestSummary, error) {
+	summary := IngestSummary{Changes: make([]IngestChange, 0)}
+
+	dates := make([]string, 0, len(data))
+	for date := range data {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	tx, err := r.Db.BeginTx(r.ctx, nil)
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback()
+
+	for _, date := range dates {
+		almanax := data[date]
+		action, err := backfillAlmanaxDay(tx, date, &almanax, gameVersion, releasedAt)
+		if err != nil {
+			return summary, fmt.Errorf("could not backfill almanax of %s: %w", date, err)
+		}
+		summary.add(date, action)
+	}
+
+	return summary, tx.Commit()
+}
+
+type almanaxHistoryRow struct {
+	id          int64
+	bonusID     int64
+	tributeID   int64
+	gameVersion string
+	createdAt   time.Time
+}
+
+func backfillAlmanaxDay(tx *sql.Tx, date string, almanax *dodumap.MappedMultilangNPCAlmanaxUnity, gameVersion string, releasedAt time.Time) (IngestAction, error) {
+	bonusTypeID, err := upsertBonusType(tx, almanax)
+	if err != nil {
+		return "", err
+	}
+
+	bonusID, err := upsertBonus(tx, bonusTypeID, almanax)
+	if err != nil {
+		return "", err
+	}
+
+	tributeID, err := upsertTribute(tx, almanax)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := tx.Query(`SELECT id, bonus_id, tribute_id, game_version, created_at FROM almanax WHERE date = ? ORDER BY created_at ASC, id ASC`, date)
+	if err != nil {
+		return "", err
+	}
+	var history []almanaxHistoryRow
+	for rows.Next() {
+		var row almanaxHistoryRow
+		if err = rows.Scan(&row.id, &row.bonusID, &row.tributeID, &row.gameVersion, &row.createdAt); err != nil {
+			rows.Close()
+			return "", err
+		}
+		history = append(history, row)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	// prev was valid when the release came out, next replaced it later
+	var prev, next *almanaxHistoryRow
+	for i := range history {
+		if history[i].gameVersion == gameVersion {
+			return IngestUnchanged, nil
+		}
+		if history[i].createdAt.After(releasedAt) {
+			next = &history[i]
+			break
+		}
+		prev = &history[i]
+	}
+
+	sameAssignment := func(row *almanaxHistoryRow) bool {
+		return row != nil && row.bonusID == bonusID && row.tributeID == tributeID
+	}
+	if sameAssignment(prev) {
+		return IngestUnchanged, nil
+	}
+
+	if prev != nil {
+		query := `UPDATE almanax SET deleted_at = ?, updated_at = datetime('now') WHERE id = ?`
+		if _, err = tx.Exec(query, sqliteTime(releasedAt), prev.id); err != nil {
+			return "", err
+		}
+	}
+
+	if sameAssignment(next) {
+		// the newer release only kept the assignment, it is valid since this one
+		query := `UPDATE almanax SET created_at = ?, updated_at = datetime('now') WHERE id = ?`
+		_, err = tx.Exec(query, sqliteTime(releasedAt), next.id)
+		return IngestUpdated, err
+	}
+
+	action := IngestInserted
+	var replacedAt any
+	if next != nil {
+		replacedAt = sqliteTime(next.createdAt)
+	} else if prev != nil {
+		action = IngestUpdated
+	}
+
+	query := `
+		INSERT INTO almanax (bonus_id, tribute_id, date, reward_kamas, experience_ratio, optimal_level, duration, game_version, created_at, updated_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), ?)`
+	_, err = tx.Exec(query, bonusID, tributeID, date, almanax.RewardKamas, almanax.ExperienceRatio, almanax.OptimalLevel, almanax.Duration,
+		gameVersion, sqliteTime(releasedAt), replacedAt)
+	return action, err
+}
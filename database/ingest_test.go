@@ -0,0 +1,123 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/dofusdude/dodumap"
+)
+
+func countRows(t *testing.T, repo *Repository, table string) int {
+	t.Helper()
+
+	var count int
+	if err := repo.Db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func ingestDays() map[string]dodumap.MappedMultilangNPCAlmanaxUnity {
+	return map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		"2024-05-01": testAlmanax("Loot", 289, 3),
+		"2024-05-02": testAlmanax("Experience", 289, 5),
+		"2024-05-03": testAlmanax("Harvest", 303, 1),
+	}
+}
+
+func TestUpdateFutureTwiceIsUnchanged(t *testing.T) {
+	repo := testRepository(t)
+
+	summary, err := repo.UpdateFuture(ingestDays(), "3.0.0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Inserted != 3 || len(summary.Changes) != 3 {
+		t.Errorf("Expected 3 inserted days, got %+v", summary)
+	}
+
+	summary, err = repo.UpdateFuture(ingestDays(), "3.0.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Unchanged != 3 || summary.Inserted != 0 || summary.Updated != 0 || len(summary.Changes) != 0 {
+		t.Errorf("Expected a second run to change nothing, got %+v", summary)
+	}
+	if rows := countRows(t, repo, "almanax"); rows != 3 {
+		t.Error("Expected 3 almanax rows, got ", rows)
+	}
+}
+
+func TestUpdateFutureDryRun(t *testing.T) {
+	repo := testRepository(t)
+
+	if _, err := repo.UpdateFuture(ingestDays(), "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := ingestDays()
+	changed["2024-05-02"] = testAlmanax("Loot", 289, 3)
+	changed["2024-05-04"] = testAlmanax("Harvest", 303, 1)
+
+	tables := []string{"almanax", "bonus_types", "bonus", "tribute"}
+	before := make(map[string]int)
+	for _, table := range tables {
+		before[table] = countRows(t, repo, table)
+	}
+
+	summary, err := repo.UpdateFuture(changed, "3.1.0", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Inserted != 1 || summary.Updated != 1 || summary.Unchanged != 2 {
+		t.Errorf("Expected the dry run to report 1 inserted and 1 updated day, got %+v", summary)
+	}
+
+	for _, table := range tables {
+		if rows := countRows(t, repo, table); rows != before[table] {
+			t.Errorf("Expected %d rows in %s after the dry run, got %d", before[table], table, rows)
+		}
+	}
+
+	current, err := repo.GetAlmanaxByDateRange("2024-05-01", "2024-05-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) != 3 || current[1].BonusType.NameEn != "Experience" {
+		t.Error("Expected the dry run to keep the current days, got ", almanaxDates(current))
+	}
+}
+
+func TestUpdateFutureRollsBackOnFailure(t *testing.T) {
+	repo := testRepository(t)
+
+	if _, err := repo.UpdateFuture(ingestDays(), "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// the last day of the run fails after the ones before were written
+	trigger := `
+		CREATE TRIGGER fail_almanax BEFORE INSERT ON almanax WHEN NEW.date = '2024-05-04'
+		BEGIN SELECT RAISE(ABORT, 'broken day'); END`
+	if _, err := repo.Db.Exec(trigger); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := ingestDays()
+	changed["2024-05-01"] = testAlmanax("Experience", 303, 2)
+	changed["2024-05-04"] = testAlmanax("Harvest", 303, 1)
+
+	if _, err := repo.UpdateFuture(changed, "3.1.0", false); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+
+	if rows := countRows(t, repo, "almanax"); rows != 3 {
+		t.Error("Expected the failed update to leave 3 almanax rows, got ", rows)
+	}
+	history, err := repo.GetAlmanaxHistory("2024-05-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Almanax.DeletedAt != nil || history[0].BonusType.NameEn != "Loot" {
+		t.Error("Expected the first day to be untouched by the failed update, got ", len(history), " assignments")
+	}
+}
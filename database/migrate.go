@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/dofusdude/doduapi/migrations"
+	"github.com/stelzo/migrate/v4"
+	"github.com/stelzo/migrate/v4/database/sqlite3"
+	"github.com/stelzo/migrate/v4/source/iofs"
+)
+
+// Migrator runs the embedded migrations on the writer connection. Do not close it, that would close the repository.
+func (r *Repository) Migrator() (*migrate.Migrate, error) {
+	dbDriver, err := sqlite3.WithInstance(r.Db, &sqlite3.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	fileSource, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", fileSource, "almanax", dbDriver)
+}
+
+// MigrateUp applies all pending migrations. It reports whether anything changed.
+func (r *Repository) MigrateUp() (bool, error) {
+	m, err := r.Migrator()
+	if err != nil {
+		return false, err
+	}
+
+	if err = m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AvailableMigrations lists the versions of all embedded migrations in ascending order.
+func AvailableMigrations() ([]uint, error) {
+	fileSource, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer fileSource.Close()
+
+	version, err := fileSource.First()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []uint{version}
+	for {
+		version, err = fileSource.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+}
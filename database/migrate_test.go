@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/dofusdude/doduapi/migrations"
+)
+
+func tableExists(t *testing.T, repo *Repository, table string) bool {
+	t.Helper()
+
+	var count int
+	err := repo.Db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestAvailableMigrations(t *testing.T) {
+	available, err := AvailableMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(available) != len(files) {
+		t.Fatalf("Expected %d migrations, got %v", len(files), available)
+	}
+	for i, version := range available {
+		if version != uint(i+1) {
+			t.Errorf("Expected migration %d at position %d, got %d", i+1, i, version)
+		}
+	}
+}
+
+func TestMigrateUp(t *testing.T) {
+	repo, err := OpenRepository(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	changed, err := repo.MigrateUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("Expected the first migration to change the database")
+	}
+	if !tableExists(t, repo, "update_delivery") {
+		t.Error("Expected the latest migration to be applied")
+	}
+
+	changed, err = repo.MigrateUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("Expected a second migration to change nothing")
+	}
+}
+
+func TestMigrateGotoAndForce(t *testing.T) {
+	repo := testRepository(t)
+
+	m, err := repo.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Migrate(6); err != nil {
+		t.Fatal(err)
+	}
+	version, dirty, err := m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 6 || dirty {
+		t.Errorf("Expected clean version 6 after goto, got %d dirty %v", version, dirty)
+	}
+	if tableExists(t, repo, "update_delivery") {
+		t.Error("Expected goto to revert the later migration")
+	}
+
+	// a migration that failed halfway leaves the version dirty until it is forced
+	if _, err = repo.Db.Exec(`UPDATE schema_migrations SET version = 7, dirty = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Migrate(7); err == nil {
+		t.Error("Expected a dirty database to refuse migrating")
+	}
+
+	if err = m.Force(6); err != nil {
+		t.Fatal(err)
+	}
+	version, dirty, err = m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 6 || dirty {
+		t.Errorf("Expected clean version 6 after force, got %d dirty %v", version, dirty)
+	}
+
+	if _, err = repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if !tableExists(t, repo, "update_delivery") {
+		t.Error("Expected migrating up after force to apply the reverted migration")
+	}
+
+	available, err := AvailableMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _, _ = m.Version(); version != available[len(available)-1] {
+		t.Error("Expected the latest version after migrating up, got ", version)
+	}
+}
@@ -0,0 +1,24 @@
+package database
+
+// RecordUpdateDelivery stores the delivery id of an update hook call. It reports false if the id was seen before, so
+// the call is a replay or a redelivery of an update that already happened.
+func (r *Repository) RecordUpdateDelivery(deliveryID string, version string) (bool, error) {
+	query := `INSERT INTO update_delivery (delivery_id, version, received_at) VALUES (?, ?, datetime('now'))
+	          ON CONFLICT (delivery_id) DO NOTHING`
+	result, err := r.Db.ExecContext(r.ctx, query, deliveryID, version)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ForgetUpdateDelivery removes a delivery id again, so a delivery whose update could not be started can be retried.
+func (r *Repository) ForgetUpdateDelivery(deliveryID string) error {
+	_, err := r.Db.ExecContext(r.ctx, `DELETE FROM update_delivery WHERE delivery_id = ?`, deliveryID)
+	return err
+}
@@ -0,0 +1,28 @@
+package database
+
+import "time"
+
+type Webhook struct {
+	ID                int64      `db:"id"`
+	Format            string     `db:"format"`
+	Url               string     `db:"url"`
+	Lang              string     `db:"lang"`
+	BonusTypes        []string   `db:"bonus_types"` // stored comma separated, empty means all
+	LastDeliveredDate *string    `db:"last_delivered_date"`
+	FailedDays        int        `db:"failed_days"` // days in a row without a delivery
+	DisabledAt        *time.Time `db:"disabled_at"`
+	CreatedAt         time.Time  `db:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at"`
+	DeletedAt         *time.Time `db:"deleted_at"`
+}
+
+type WebhookDelivery struct {
+	ID         int64     `db:"id"`
+	WebhookID  int64     `db:"webhook_id"`
+	Date       string    `db:"date"`
+	Attempt    int       `db:"attempt"`
+	StatusCode int       `db:"status_code"`
+	Error      string    `db:"error"`
+	Success    bool      `db:"success"`
+	CreatedAt  time.Time `db:"created_at"`
+}
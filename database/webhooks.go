@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+func joinBonusTypes(bonusTypes []string) string {
+	return strings.Join(bonusTypes, ",")
+}
+
+func splitBonusTypes(bonusTypes string) []string {
+	if bonusTypes == "" {
+		return []string{}
+	}
+	return strings.Split(bonusTypes, ",")
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (Webhook, error) {
+	var webhook Webhook
+	var bonusTypes string
+	var lastDeliveredDate sql.NullString
+	var disabledAt sql.NullTime
+	err := row.Scan(&webhook.ID, &webhook.Format, &webhook.Url, &webhook.Lang, &bonusTypes, &lastDeliveredDate,
+		&webhook.FailedDays, &disabledAt, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return webhook, err
+	}
+
+	webhook.BonusTypes = splitBonusTypes(bonusTypes)
+	if lastDeliveredDate.Valid {
+		webhook.LastDeliveredDate = &lastDeliveredDate.String
+	}
+	if disabledAt.Valid {
+		webhook.DisabledAt = &disabledAt.Time
+	}
+
+	return webhook, nil
+}
+
+func (r *Repository) CreateWebhook(webhook *Webhook) (int64, error) {
+	query := `INSERT INTO webhook (format, url, lang, bonus_types, created_at, updated_at)
+	          VALUES (?, ?, ?, ?, datetime('now'), datetime('now'))`
+	result, err := r.Db.Exec(query, webhook.Format, webhook.Url, webhook.Lang, joinBonusTypes(webhook.BonusTypes))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetWebhook returns sql.ErrNoRows if the webhook does not exist or was deleted.
+func (r *Repository) GetWebhook(id int64) (Webhook, error) {
+	query := `SELECT id, format, url, lang, bonus_types, last_delivered_date, failed_days, disabled_at, created_at, updated_at
+	          FROM webhook WHERE id = ? AND deleted_at IS NULL`
+	return scanWebhook(r.readDb.QueryRowContext(r.ctx, query, id))
+}
+
+func (r *Repository) GetWebhooks() ([]Webhook, error) {
+	query := `SELECT id, format, url, lang, bonus_types, last_delivered_date, failed_days, disabled_at, created_at, updated_at
+	          FROM webhook WHERE deleted_at IS NULL ORDER BY id ASC`
+	rows, err := r.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetWebhooksDueForDate lists all enabled webhooks that did not get the almanax of date yet.
+func (r *Repository) GetWebhooksDueForDate(date string) ([]Webhook, error) {
+	query := `SELECT id, format, url, lang, bonus_types, last_delivered_date, failed_days, disabled_at, created_at, updated_at
+	          FROM webhook WHERE deleted_at IS NULL AND disabled_at IS NULL AND (last_delivered_date IS NULL OR last_delivered_date < ?) ORDER BY id ASC`
+	rows, err := r.query(query, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) UpdateWebhook(webhook *Webhook) error {
+	query := `
+		UPDATE webhook
+		SET format = ?, url = ?, lang = ?, bonus_types = ?, failed_days = 0, disabled_at = NULL, updated_at = datetime('now')
+		WHERE id = ? AND deleted_at IS NULL`
+	result, err := r.Db.Exec(query, webhook.Format, webhook.Url, webhook.Lang, joinBonusTypes(webhook.BonusTypes), webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *Repository) MarkWebhookDelivered(id int64, date string) error {
+	query := `UPDATE webhook SET last_delivered_date = ?, failed_days = 0, updated_at = datetime('now') WHERE id = ?`
+	_, err := r.Db.Exec(query, date, id)
+	return err
+}
+
+// MarkWebhookFailed gives up on the day for the webhook. It is disabled once it failed disableAfter days in a row,
+// never when disableAfter is 0. It reports whether the webhook got disabled.
+func (r *Repository) MarkWebhookFailed(id int64, date string, disableAfter int) (bool, error) {
+	query := `
+		UPDATE webhook
+		SET last_delivered_date = ?, failed_days = failed_days + 1,
+		    disabled_at = CASE WHEN ? > 0 AND failed_days + 1 >= ? THEN datetime('now') ELSE disabled_at END,
+		    updated_at = datetime('now')
+		WHERE id = ?
+		RETURNING disabled_at IS NOT NULL`
+	var disabled bool
+	err := r.Db.QueryRow(query, date, disableAfter, disableAfter, id).Scan(&disabled)
+	return disabled, err
+}
+
+// CountFailedWebhookDeliveries counts the failed attempts to deliver the almanax of date to the webhook.
+func (r *Repository) CountFailedWebhookDeliveries(webhookID int64, date string) (int, error) {
+	query := `SELECT COUNT(*) FROM webhook_delivery WHERE webhook_id = ? AND date = ? AND success = 0`
+	var count int
+	err := r.readDb.QueryRowContext(r.ctx, query, webhookID, date).Scan(&count)
+	return count, err
+}
+
+// DeleteWebhook only marks the webhook as deleted, so its delivery logs stay intact.
+func (r *Repository) DeleteWebhook(id int64) error {
+	query := `UPDATE webhook SET deleted_at = datetime('now') WHERE id = ? AND deleted_at IS NULL`
+	result, err := r.Db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateWebhookDelivery(delivery *WebhookDelivery) (int64, error) {
+	query := `INSERT INTO webhook_delivery (webhook_id, date, attempt, status_code, error, success, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?, datetime('now'))`
+	result, err := r.Db.Exec(query, delivery.WebhookID, delivery.Date, delivery.Attempt, delivery.StatusCode,
+		delivery.Error, delivery.Success)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetWebhookDeliveries returns the latest delivery attempts of a webhook, newest first.
+func (r *Repository) GetWebhookDeliveries(webhookID int64, limit int) ([]WebhookDelivery, error) {
+	query := `SELECT id, webhook_id, date, attempt, status_code, error, success, created_at
+	          FROM webhook_delivery WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`
+	rows, err := r.query(query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Date, &delivery.Attempt, &delivery.StatusCode,
+			&delivery.Error, &delivery.Success, &delivery.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
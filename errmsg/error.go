@@ -25,14 +25,27 @@ var (
 
 	ERR_NOT_FOUND         = "NOT_FOUND"
 	ERR_NOT_FOUND_MESSAGE = "The requested resource was not found."
+
+	ERR_UNAUTHORIZED         = "UNAUTHORIZED"
+	ERR_UNAUTHORIZED_MESSAGE = "The request is missing a valid token."
+
+	ERR_FORBIDDEN         = "FORBIDDEN"
+	ERR_FORBIDDEN_MESSAGE = "The token is valid but not allowed to do this."
+
+	ERR_RATE_LIMITED         = "RATE_LIMITED"
+	ERR_RATE_LIMITED_MESSAGE = "Too many requests. Please slow down and try again after the time in Retry-After."
+
+	ERR_CONFLICT         = "CONFLICT"
+	ERR_CONFLICT_MESSAGE = "The operation is already running. Please try again when it is done."
 )
 
 type ApiError struct {
-	Status  int    `json:"status"`
-	Error   string `json:"error"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Status    int    `json:"status"`
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
 }
 
 func WriteInvalidUrlResponse(w http.ResponseWriter, details string) {
@@ -43,6 +56,22 @@ func WriteNotFoundResponse(w http.ResponseWriter, details string) {
 	WriteErrorResponse(w, http.StatusNotFound, ERR_NOT_FOUND, ERR_NOT_FOUND_MESSAGE, details)
 }
 
+func WriteUnauthorizedResponse(w http.ResponseWriter, details string) {
+	WriteErrorResponse(w, http.StatusUnauthorized, ERR_UNAUTHORIZED, ERR_UNAUTHORIZED_MESSAGE, details)
+}
+
+func WriteForbiddenResponse(w http.ResponseWriter, details string) {
+	WriteErrorResponse(w, http.StatusForbidden, ERR_FORBIDDEN, ERR_FORBIDDEN_MESSAGE, details)
+}
+
+func WriteConflictResponse(w http.ResponseWriter, details string) {
+	WriteErrorResponse(w, http.StatusConflict, ERR_CONFLICT, ERR_CONFLICT_MESSAGE, details)
+}
+
+func WriteTooManyRequestsResponse(w http.ResponseWriter, details string) {
+	WriteErrorResponse(w, http.StatusTooManyRequests, ERR_RATE_LIMITED, ERR_RATE_LIMITED_MESSAGE, details)
+}
+
 func WriteServerErrorResponse(w http.ResponseWriter, details string) {
 	WriteErrorResponse(w, http.StatusInternalServerError, ERR_SERVER_ERROR, ERR_SERVER_MESSAGE, details)
 }
@@ -59,21 +88,23 @@ func WriteInvalidJsonResponse(w http.ResponseWriter, details string) {
 	WriteErrorResponse(w, http.StatusBadRequest, ERR_INVALID_JSON_BODY, ERR_INVALID_JSON_MESSAGE, details)
 }
 
+// WriteErrorResponse takes the request id from the X-Request-Id header that the router already set on w.
 func WriteErrorResponse(w http.ResponseWriter, status int, code, message, details string) {
 	apiErr := ApiError{
-		Status:  status,
-		Error:   http.StatusText(status),
-		Code:    code,
-		Message: message,
-		Details: details,
+		Status:    status,
+		Error:     http.StatusText(status),
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestId: w.Header().Get("X-Request-Id"),
 	}
 
 	if status == http.StatusInternalServerError {
-		log.Error("Internal Server Error", "code", code, "message", message, "details", details)
+		log.Error("Internal Server Error", "request_id", apiErr.RequestId, "code", code, "message", message, "details", details)
 	}
 
 	if status == http.StatusBadRequest {
-		log.Warn("Bad Request", "code", code, "message", message, "details", details)
+		log.Warn("Bad Request", "request_id", apiErr.RequestId, "code", code, "message", message, "details", details)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
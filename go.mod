@@ -23,7 +23,6 @@ require (
 	github.com/spf13/viper v1.19.0
 	github.com/stelzo/migrate/v4 v4.18.2
 	github.com/zyedidia/generic v1.2.1
-
 )
 
 require (
@@ -47,6 +46,7 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/klauspost/compress v1.17.11 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/magiconair/properties v1.8.9 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
@@ -7,9 +7,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"sync/atomic"
 
 	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/almanax"
 	"github.com/dofusdude/doduapi/config"
 	"github.com/dofusdude/doduapi/database"
 	e "github.com/dofusdude/doduapi/errmsg"
@@ -37,10 +38,31 @@ var (
 
 	mountAllowedExpandFields     = []string{"effects"}
 	setAllowedExpandFields       = utils.Concat(mountAllowedExpandFields, []string{"equipment_ids"})
-	itemAllowedExpandFields      = utils.Concat(mountAllowedExpandFields, []string{"recipe", "description", "conditions"})
+	itemAllowedExpandFields      = utils.Concat(mountAllowedExpandFields, []string{"recipe", "description", "conditions", "almanax"})
 	equipmentAllowedExpandFields = utils.Concat(itemAllowedExpandFields, []string{"range", "parent_set", "is_weapon", "pods", "critical_hit_probability", "critical_hit_bonus", "max_cast_per_turn", "ap_cost"})
 )
 
+// upcomingTributes maps the items to the almanax days from today on where they are the tribute.
+func upcomingTributes(r *http.Request) (map[int64][]database.TributeDate, error) {
+	repo := r.Context().Value("almanaxRepo").(*database.Repository)
+	return repo.GetUpcomingTributes(almanax.GameToday().Format("2006-01-02"))
+}
+
+// singleItemAlmanax looks up the upcoming tributes of a single item. Single items have every other field already, so
+// only the almanax lookup is opt-in and other fields[item] values are ignored.
+func singleItemAlmanax(expansions *set.Set[string], ankamaId int, r *http.Request) ([]APIItemAlmanax, error) {
+	if !expansions.Has("almanax") {
+		return nil, nil
+	}
+
+	repo := r.Context().Value("almanaxRepo").(*database.Repository)
+	tributes, err := repo.GetUpcomingTributesByItem(almanax.GameToday().Format("2006-01-02"), int64(ankamaId))
+	if err != nil {
+		return nil, err
+	}
+	return RenderItemAlmanax(tributes), nil
+}
+
 func GetRecipeIfExists(itemId int, txn *memdb.Txn) (mapping.MappedMultilangRecipe, bool) {
 	var err error
 	var raw interface{}
@@ -99,43 +121,6 @@ func MinMaxLevelMeiliFilterFromParams(filterMinLevel string, filterMaxLevel stri
 	return filterString, nil
 }
 
-type UpdateMessage struct {
-	Version string `json:"version"`
-}
-
-// update
-func UpdateHandler(w http.ResponseWriter, r *http.Request) {
-	var updateMessage UpdateMessage
-	if err := json.NewDecoder(r.Body).Decode(&updateMessage); err != nil {
-		e.WriteInvalidJsonResponse(w, err.Error())
-		return
-	}
-
-	var release string
-	if config.IsBeta {
-		release = "beta"
-	} else {
-		release = "main"
-	}
-
-	config.ReleaseUrl = fmt.Sprintf("https://github.com/dofusdude/dofus3-%s/releases/download/%s", release, updateMessage.Version)
-
-	log.Info("Updating to version", updateMessage.Version)
-	err := utils.DownloadImages(config.DockerMountDataPath, config.ReleaseUrl)
-	if err != nil {
-		e.WriteServerErrorResponse(w, "Could not download images: "+err.Error())
-		return
-	}
-
-	newVersion := utils.GameVersion{
-		Version:     updateMessage.Version,
-		Release:     release,
-		UpdateStamp: time.Now(),
-	}
-
-	UpdateChan <- newVersion
-}
-
 // listings
 
 // all
@@ -544,6 +529,14 @@ func ListItems(itemType string, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var tributes map[int64][]database.TributeDate
+	if expansions.Has("almanax") {
+		if tributes, err = upcomingTributes(r); err != nil {
+			e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+			return
+		}
+	}
+
 	txn := database.Db.Txn(false)
 	defer txn.Abort()
 
@@ -613,6 +606,10 @@ func ListItems(itemType string, w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if expansions.Has("almanax") {
+			item.Almanax = RenderItemAlmanax(tributes[int64(p.AnkamaId)])
+		}
+
 		// equipment extra fields
 		mIsWeapon := p.Type.SuperTypeId == 2 // is weapon
 		if expansions.Has("is_weapon") {
@@ -741,7 +738,7 @@ func getLimitInBoundary(limitStr string) (int64, error) {
 
 // search
 func SearchMounts(w http.ResponseWriter, r *http.Request) {
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
 	var err error
@@ -794,7 +791,17 @@ func SearchMounts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	searchResp, err := index.Search(query, request)
+	result, err := runSearch(index, query, request, func() ([]searchHit, error) {
+		return fallbackSearchMounts(query, lang, func(mount *mapping.MappedMultilangMount) bool {
+			if filterFamilyName != "" && !strings.EqualFold(mount.FamilyName[lang], filterFamilyName) {
+				return false
+			}
+			if filterFamilyIdStr != "" && strconv.Itoa(mount.FamilyId) != filterFamilyIdStr {
+				return false
+			}
+			return true
+		})
+	})
 	if err != nil {
 		e.WriteServerErrorResponse(w, "Could not search: "+err.Error())
 		return
@@ -803,7 +810,8 @@ func SearchMounts(w http.ResponseWriter, r *http.Request) {
 	utils.RequestsTotal.Inc()
 	utils.RequestsMountsSearch.Inc()
 
-	if searchResp.EstimatedTotalHits == 0 {
+	writeDegradedHeader(w, result.Degraded)
+	if len(result.Hits) == 0 {
 		e.WriteNotFoundResponse(w, "No results found.")
 		return
 	}
@@ -812,9 +820,8 @@ func SearchMounts(w http.ResponseWriter, r *http.Request) {
 	defer txn.Abort()
 
 	var mounts []APIMount
-	for _, hit := range searchResp.Hits {
-		indexed := hit.(map[string]interface{})
-		itemId := int(indexed["id"].(float64))
+	for _, hit := range result.Hits {
+		itemId := hit.Id
 
 		raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), "mounts"), "id", itemId)
 		if err != nil {
@@ -840,7 +847,7 @@ func SearchMounts(w http.ResponseWriter, r *http.Request) {
 }
 
 func SearchSets(w http.ResponseWriter, r *http.Request) {
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
 	query := r.URL.Query().Get("query")
@@ -906,7 +913,30 @@ func SearchSets(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	searchResp, err := index.Search(query, request)
+	result, err := runSearch(index, query, request, func() ([]searchHit, error) {
+		filterMinLevelInt, filterMaxLevelInt, _ := MinMaxLevelInt(filterMinLevel, filterMaxLevel, "highest_equipment_level")
+		return fallbackSearchSets(query, lang, func(set *mapping.MappedMultilangSetUnity) bool {
+			if filterMinLevel != "" && set.Level < filterMinLevelInt {
+				return false
+			}
+			if filterMaxLevel != "" && set.Level > filterMaxLevelInt {
+				return false
+			}
+			if filterContainsCosmeticsOnlyStr != "" {
+				filterIsCosmetic, _ := strconv.ParseBool(filterContainsCosmeticsOnlyStr)
+				if set.ContainsCosmeticsOnly != filterIsCosmetic {
+					return false
+				}
+			}
+			if filterContainsCosmeticsStr != "" {
+				filterIsCosmetic, _ := strconv.ParseBool(filterContainsCosmeticsStr)
+				if set.ContainsCosmetics != filterIsCosmetic {
+					return false
+				}
+			}
+			return true
+		})
+	})
 	if err != nil {
 		e.WriteServerErrorResponse(w, "Could not search: "+err.Error())
 		return
@@ -915,7 +945,8 @@ func SearchSets(w http.ResponseWriter, r *http.Request) {
 	utils.RequestsTotal.Inc()
 	utils.RequestsSetsSearch.Inc()
 
-	if searchResp.EstimatedTotalHits == 0 {
+	writeDegradedHeader(w, result.Degraded)
+	if len(result.Hits) == 0 {
 		e.WriteNotFoundResponse(w, "No results found.")
 		return
 	}
@@ -924,9 +955,8 @@ func SearchSets(w http.ResponseWriter, r *http.Request) {
 	defer txn.Abort()
 
 	var sets []APIListSet
-	for _, hit := range searchResp.Hits {
-		indexed := hit.(map[string]interface{})
-		itemId := int(indexed["id"].(float64))
+	for _, hit := range result.Hits {
+		itemId := hit.Id
 
 		raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), "sets"), "id", itemId)
 		if err != nil {
@@ -952,7 +982,7 @@ func SearchSets(w http.ResponseWriter, r *http.Request) {
 }
 
 func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
 	query := r.URL.Query().Get("query")
@@ -988,6 +1018,18 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	highlight, err := parseHighlightParam(r.URL.Query().Get("highlight"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "highlight must be a boolean.")
+		return
+	}
+
+	facets, err := parseFacetsParam(r.URL.Query().Get("facets"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "facets has invalid fields: "+err.Error())
+		return
+	}
+
 	typeFiltering := strings.ToLower(r.URL.Query().Get("filter[type.name_id]"))
 	exceptions := []string{"mount", "set"}
 	filterset := parseFields(typeFiltering)
@@ -1030,10 +1072,9 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 		filterString += "(NOT type.name_id=" + strings.Join(removedTypes.Keys(), " AND NOT type.name_id=") + ")"
 	}
 
-	wordScoreWeight := 0.5
-	typoScoreWeight := 0.5
-
 	searchChans := make([]chan []ApiAllSearchResultScore, 0)
+	var degraded atomic.Bool
+	var itemFacets map[string]map[string]int
 
 	indicesHasItem := parsedIndices.Has("items-equipment") || parsedIndices.Has("items-consumables") || parsedIndices.Has("items-resources") || parsedIndices.Has("items-quest_items") || parsedIndices.Has("items-cosmetics")
 	needItemSearch := parsedIndices.Size() == 0 || indicesHasItem
@@ -1050,24 +1091,33 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 				Filter:                  filterString,
 				ShowRankingScoreDetails: true,
 			}
-
-			var searchResp *meilisearch.SearchResponse
-			if searchResp, err = index.Search(query, request); err != nil {
+			if highlight {
+				withHighlight(request, []string{"name", "description"})
+			}
+			request.Facets = facets
+
+			found, err := runSearch(index, query, request, func() ([]searchHit, error) {
+				return fallbackSearchItems(query, lang, func(item *mapping.MappedMultilangItemUnity) bool {
+					enTypeName := strings.ToLower(strings.ReplaceAll(item.Type.Name["en"], " ", "-"))
+					if removedTypes.Has(enTypeName) {
+						return false
+					}
+					return additiveTypes.Size() == 0 || additiveTypes.Has(enTypeName)
+				})
+			})
+			if err != nil {
 				e.WriteServerErrorResponse(w, "Failed to search for query: "+err.Error())
+				itemRetChan <- nil
 				return
 			}
+			if found.Degraded {
+				degraded.Store(true)
+			}
+			itemFacets = found.Facets
 
 			items := make([]ApiAllSearchResultScore, 0)
-			for _, hit := range searchResp.Hits {
-				indexed := hit.(map[string]interface{})
-				//stuffType := indexed["stuff_type"].(map[string]interface{})["name_id"].(string)
-
-				wordScore := indexed["_rankingScoreDetails"].(map[string]interface{})["words"].(map[string]interface{})["score"].(float64)
-				typoScore := indexed["_rankingScoreDetails"].(map[string]interface{})["typo"].(map[string]interface{})["score"].(float64)
-
-				score := wordScore*wordScoreWeight + typoScore*typoScoreWeight
-
-				itemId := int(indexed["id"].(float64))
+			for _, hit := range found.Hits {
+				itemId := hit.Id
 				txn := database.Db.Txn(false)
 				raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), "all_items"), "id", itemId)
 
@@ -1078,7 +1128,7 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 				}
 
 				if raw == nil {
-					log.Warn("Item not found in memdb.", "id", itemId)
+					requestLog(r).Warn("Item not found in memdb.", "id", itemId)
 					continue
 				}
 
@@ -1134,10 +1184,13 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 					},
 					ItemFields: itemInclude,
 				}
+				if highlight {
+					result.Highlight = renderHighlight(hit, item.Name[lang], query)
+				}
 
 				items = append(items, ApiAllSearchResultScore{
 					Result: result,
-					Score:  score,
+					Score:  hit.Score,
 				})
 			}
 
@@ -1169,24 +1222,25 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 				//Filter:                  filterString,
 				ShowRankingScoreDetails: true,
 			}
+			if highlight {
+				withHighlight(request, []string{"name"})
+			}
 
-			var searchResp *meilisearch.SearchResponse
-			if searchResp, err = setIndex.Search(query, request); err != nil {
+			found, err := runSearch(setIndex, query, request, func() ([]searchHit, error) {
+				return fallbackSearchSets(query, lang, nil)
+			})
+			if err != nil {
 				e.WriteServerErrorResponse(w, "Failed to search for query: "+err.Error())
 				setRetChan <- nil
 				return
 			}
+			if found.Degraded {
+				degraded.Store(true)
+			}
 
 			sets := make([]ApiAllSearchResultScore, 0)
-			for _, hit := range searchResp.Hits {
-				indexed := hit.(map[string]interface{})
-
-				wordScore := indexed["_rankingScoreDetails"].(map[string]interface{})["words"].(map[string]interface{})["score"].(float64)
-				typoScore := indexed["_rankingScoreDetails"].(map[string]interface{})["typo"].(map[string]interface{})["score"].(float64)
-
-				score := wordScore*wordScoreWeight + typoScore*typoScoreWeight
-
-				setId := int(indexed["id"].(float64))
+			for _, hit := range found.Hits {
+				setId := hit.Id
 
 				txn := database.Db.Txn(false)
 				raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), "sets"), "id", setId)
@@ -1212,10 +1266,13 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 					},
 					ItemFields: nil,
 				}
+				if highlight {
+					result.Highlight = renderHighlight(hit, item.Name[lang], query)
+				}
 
 				sets = append(sets, ApiAllSearchResultScore{
 					Result: result,
-					Score:  score,
+					Score:  hit.Score,
 				})
 			}
 
@@ -1245,24 +1302,25 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 				Limit:                   searchLimit * 3,
 				ShowRankingScoreDetails: true,
 			}
+			if highlight {
+				withHighlight(request, []string{"name"})
+			}
 
-			var searchResp *meilisearch.SearchResponse
-			if searchResp, err = mountIndex.Search(query, request); err != nil {
+			found, err := runSearch(mountIndex, query, request, func() ([]searchHit, error) {
+				return fallbackSearchMounts(query, lang, nil)
+			})
+			if err != nil {
 				e.WriteServerErrorResponse(w, "Failed to search for query: "+err.Error())
 				mountRetChan <- nil
 				return
 			}
+			if found.Degraded {
+				degraded.Store(true)
+			}
 
 			mounts := make([]ApiAllSearchResultScore, 0)
-			for _, hit := range searchResp.Hits {
-				indexed := hit.(map[string]interface{})
-
-				wordScore := indexed["_rankingScoreDetails"].(map[string]interface{})["words"].(map[string]interface{})["score"].(float64)
-				typoScore := indexed["_rankingScoreDetails"].(map[string]interface{})["typo"].(map[string]interface{})["score"].(float64)
-
-				score := wordScore*wordScoreWeight + typoScore*typoScoreWeight
-
-				mountId := int(indexed["id"].(float64))
+			for _, hit := range found.Hits {
+				mountId := hit.Id
 
 				txn := database.Db.Txn(false)
 				raw, err := txn.First(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), "mounts"), "id", mountId)
@@ -1288,10 +1346,13 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 					},
 					ItemFields: nil,
 				}
+				if highlight {
+					result.Highlight = renderHighlight(hit, item.Name[lang], query)
+				}
 
 				mounts = append(mounts, ApiAllSearchResultScore{
 					Result: result,
-					Score:  score,
+					Score:  hit.Score,
 				})
 			}
 
@@ -1310,6 +1371,10 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 		merged = append(merged, searchResults...)
 	}
 
+	utils.RequestsTotal.Inc()
+	utils.RequestsSearchTotal.Inc()
+
+	writeDegradedHeader(w, degraded.Load())
 	if len(merged) == 0 {
 		e.WriteNotFoundResponse(w, "No results found.")
 		return
@@ -1331,7 +1396,14 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.WriteCacheHeader(&w)
-	err = json.NewEncoder(w).Encode(stuffs)
+	if len(facets) > 0 {
+		if itemFacets == nil {
+			itemFacets = make(map[string]map[string]int)
+		}
+		err = json.NewEncoder(w).Encode(APIFacetedAllSearch{Results: stuffs, Facets: itemFacets})
+	} else {
+		err = json.NewEncoder(w).Encode(stuffs)
+	}
 	if err != nil {
 		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
 		return
@@ -1339,7 +1411,7 @@ func SearchAllIndices(w http.ResponseWriter, r *http.Request) {
 }
 
 func SearchItems(itemType string, all bool, w http.ResponseWriter, r *http.Request) {
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
 	query := r.URL.Query().Get("query")
@@ -1364,6 +1436,18 @@ func SearchItems(itemType string, all bool, w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	highlight, err := parseHighlightParam(r.URL.Query().Get("highlight"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "highlight must be a boolean.")
+		return
+	}
+
+	facets, err := parseFacetsParam(r.URL.Query().Get("facets"))
+	if err != nil {
+		e.WriteInvalidQueryResponse(w, "facets has invalid fields: "+err.Error())
+		return
+	}
+
 	typeFiltering := strings.ToLower(r.URL.Query().Get("filter[type.name_id]"))
 	filterset := parseFields(typeFiltering)
 	additiveTypes, err := includeTypes(filterset, nil)
@@ -1414,7 +1498,33 @@ func SearchItems(itemType string, all bool, w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	searchResp, err := index.Search(query, request)
+	if highlight {
+		withHighlight(request, []string{"name", "description"})
+	}
+	request.Facets = facets
+
+	result, err := runSearch(index, query, request, func() ([]searchHit, error) {
+		filterMinLevelInt, filterMaxLevelInt, _ := MinMaxLevelInt(filterMinLevel, filterMaxLevel, "level")
+		return fallbackSearchItems(query, lang, func(item *mapping.MappedMultilangItemUnity) bool {
+			if item.Type.CategoryId == 4 {
+				return false
+			}
+			if !all && utils.CategoryIdMapping(item.Type.CategoryId) != itemType {
+				return false
+			}
+			if filterMinLevel != "" && item.Level < filterMinLevelInt {
+				return false
+			}
+			if filterMaxLevel != "" && item.Level > filterMaxLevelInt {
+				return false
+			}
+			enTypeName := strings.ToLower(strings.ReplaceAll(item.Type.Name["en"], " ", "-"))
+			if removedTypes.Has(enTypeName) {
+				return false
+			}
+			return additiveTypes.Size() == 0 || additiveTypes.Has(enTypeName)
+		})
+	})
 	if err != nil {
 		e.WriteServerErrorResponse(w, "Could not search: "+err.Error())
 		return
@@ -1423,7 +1533,8 @@ func SearchItems(itemType string, all bool, w http.ResponseWriter, r *http.Reque
 	utils.RequestsTotal.Inc()
 	utils.RequestsItemsSearch.Inc()
 
-	if searchResp.EstimatedTotalHits == 0 {
+	writeDegradedHeader(w, result.Degraded)
+	if len(result.Hits) == 0 {
 		e.WriteNotFoundResponse(w, "No results found.")
 		return
 	}
@@ -1433,9 +1544,8 @@ func SearchItems(itemType string, all bool, w http.ResponseWriter, r *http.Reque
 
 	var items []APIListItem
 	var typedItems []APIListTypedItem
-	for _, hit := range searchResp.Hits {
-		indexed := hit.(map[string]interface{})
-		itemId := int(indexed["id"].(float64))
+	for _, hit := range result.Hits {
+		itemId := hit.Id
 
 		var raw interface{}
 		if all {
@@ -1450,26 +1560,39 @@ func SearchItems(itemType string, all bool, w http.ResponseWriter, r *http.Reque
 		}
 
 		if raw == nil {
-			log.Warn("Item not found in memdb.", "id", itemId)
+			requestLog(r).Warn("Item not found in memdb.", "id", itemId)
 			continue
 		}
 
 		item := raw.(*mapping.MappedMultilangItemUnity)
 		if all {
-			typedItems = append(typedItems, RenderTypedItemListEntry(item, lang))
+			typedItem := RenderTypedItemListEntry(item, lang)
+			if highlight {
+				typedItem.Highlight = renderHighlight(hit, item.Name[lang], query)
+			}
+			typedItems = append(typedItems, typedItem)
 		} else {
 			itemRendered := RenderItemListEntry(item, lang)
 			recipe, exists := GetRecipeIfExists(itemRendered.Id, txn)
 			if exists {
 				itemRendered.Recipe = RenderRecipe(recipe, database.Db)
 			}
+			if highlight {
+				itemRendered.Highlight = renderHighlight(hit, item.Name[lang], query)
+			}
 			items = append(items, itemRendered)
 		}
 	}
 
 	utils.WriteCacheHeader(&w)
 	var encodeErr error
-	if all {
+	if len(facets) > 0 {
+		if all {
+			encodeErr = json.NewEncoder(w).Encode(APIFacetedTypedItemSearch{Items: typedItems, Facets: result.Facets})
+		} else {
+			encodeErr = json.NewEncoder(w).Encode(APIFacetedItemSearch{Items: items, Facets: result.Facets})
+		}
+	} else if all {
 		encodeErr = json.NewEncoder(w).Encode(typedItems)
 	} else {
 		encodeErr = json.NewEncoder(w).Encode(items)
@@ -1570,6 +1693,8 @@ func GetSingleItemWithOptionalRecipeHandler(itemType string, w http.ResponseWrit
 	lang := r.Context().Value("lang").(string)
 	ankamaId := r.Context().Value("ankamaId").(int)
 
+	expansions := parseFields(strings.ToLower(r.URL.Query().Get("fields[item]")))
+
 	txn := database.Db.Txn(false)
 	defer txn.Abort()
 
@@ -1585,9 +1710,16 @@ func GetSingleItemWithOptionalRecipeHandler(itemType string, w http.ResponseWrit
 	}
 
 	utils.RequestsTotal.Inc()
+	itemAlmanax, err := singleItemAlmanax(expansions, ankamaId, r)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
 	utils.RequestsItemsSingle.Inc()
 
 	resource := RenderResource(raw.(*mapping.MappedMultilangItemUnity), lang)
+	resource.Almanax = itemAlmanax
 	recipe, exists := GetRecipeIfExists(ankamaId, txn)
 	if exists {
 		resource.Recipe = RenderRecipe(recipe, database.Db)
@@ -1624,6 +1756,8 @@ func GetSingleEquipmentLikeHandler(cosmetic bool, w http.ResponseWriter, r *http
 	lang := r.Context().Value("lang").(string)
 	ankamaId := r.Context().Value("ankamaId").(int)
 
+	expansions := parseFields(strings.ToLower(r.URL.Query().Get("fields[item]")))
+
 	txn := database.Db.Txn(false)
 	defer txn.Abort()
 
@@ -1646,11 +1780,18 @@ func GetSingleEquipmentLikeHandler(cosmetic bool, w http.ResponseWriter, r *http
 	}
 
 	utils.RequestsTotal.Inc()
+	itemAlmanax, err := singleItemAlmanax(expansions, ankamaId, r)
+	if err != nil {
+		e.WriteServerErrorResponse(w, "Database error while getting Almanax. "+err.Error())
+		return
+	}
+
 	utils.RequestsItemsSingle.Inc()
 
 	item := raw.(*mapping.MappedMultilangItemUnity)
 	if item.Type.SuperTypeId == 2 { // is weapon
 		weapon := RenderWeapon(item, lang)
+		weapon.Almanax = itemAlmanax
 		recipe, exists := GetRecipeIfExists(ankamaId, txn)
 		if exists {
 			weapon.Recipe = RenderRecipe(recipe, database.Db)
@@ -1663,6 +1804,7 @@ func GetSingleEquipmentLikeHandler(cosmetic bool, w http.ResponseWriter, r *http
 		}
 	} else {
 		equipment := RenderEquipment(item, lang)
+		equipment.Almanax = itemAlmanax
 		recipe, exists := GetRecipeIfExists(ankamaId, txn)
 		if exists {
 			equipment.Recipe = RenderRecipe(recipe, database.Db)
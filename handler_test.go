@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dofusdude/doduapi/almanax"
+	"github.com/dofusdude/doduapi/database"
+	"github.com/dofusdude/dodumap"
+)
+
+func TestSingleItemAlmanax(t *testing.T) {
+	repo, err := database.OpenRepository(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	if _, err = repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	today := almanax.GameToday()
+	tribute := func(itemId int, quantity int) dodumap.MappedMultilangNPCAlmanaxUnity {
+		day := dodumap.MappedMultilangNPCAlmanaxUnity{
+			Bonus:     map[string]string{"en": "More loot."},
+			BonusType: map[string]string{"en": "Loot"},
+		}
+		day.Offering.ItemId = itemId
+		day.Offering.ItemName = map[string]string{"en": "Wheat"}
+		day.Offering.Quantity = quantity
+		return day
+	}
+	days := map[string]dodumap.MappedMultilangNPCAlmanaxUnity{
+		today.AddDate(0, 0, -1).Format("2006-01-02"): tribute(289, 1),
+		today.Format("2006-01-02"):                   tribute(289, 3),
+		today.AddDate(0, 0, 1).Format("2006-01-02"):  tribute(303, 2),
+		today.AddDate(0, 0, 9).Format("2006-01-02"):  tribute(289, 7),
+	}
+	if _, err = repo.UpdateFuture(days, "3.0.0", false); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/dofus3/v1/en/items/resources/289", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "almanaxRepo", repo))
+
+	tests := []struct {
+		fields   string
+		expected []APIItemAlmanax
+	}{
+		{"", nil},
+		{"recipe", nil},
+		{"almanax", []APIItemAlmanax{
+			{Date: today.Format("2006-01-02"), Quantity: 3},
+			{Date: today.AddDate(0, 0, 9).Format("2006-01-02"), Quantity: 7},
+		}},
+		{"recipe,almanax", []APIItemAlmanax{
+			{Date: today.Format("2006-01-02"), Quantity: 3},
+			{Date: today.AddDate(0, 0, 9).Format("2006-01-02"), Quantity: 7},
+		}},
+	}
+
+	for _, test := range tests {
+		itemAlmanax, err := singleItemAlmanax(parseFields(test.fields), 289, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(itemAlmanax, test.expected) {
+			t.Errorf("fields[item]=%s: expected %v, got %v", test.fields, test.expected, itemAlmanax)
+		}
+	}
+
+	itemAlmanax, err := singleItemAlmanax(parseFields("almanax"), 421, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if itemAlmanax != nil {
+		t.Error("Expected no almanax for an item that is never a tribute, got ", itemAlmanax)
+	}
+
+	// the listings look up all items at once
+	tributes, err := upcomingTributes(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tributes) != 2 || len(tributes[289]) != 2 || len(tributes[303]) != 1 {
+		t.Error("Expected the upcoming tributes of 289 and 303, got ", tributes)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -31,14 +32,24 @@ type SearchType struct {
 	NameId string `json:"name_id"` // old "type_id"
 }
 
+type SearchParentSet struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
 type SearchIndexedItem struct {
-	Id          int             `json:"id"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	SuperType   SearchStuffType `json:"super_type"`
-	Type        SearchType      `json:"type"`
-	Level       int             `json:"level"`
-	StuffType   SearchStuffType `json:"stuff_type"`
+	Id           int              `json:"id"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	SuperType    SearchStuffType  `json:"super_type"`
+	Type         SearchType       `json:"type"`
+	Level        int              `json:"level"`
+	LevelBucket  string           `json:"level_bucket"`
+	HasParentSet bool             `json:"has_parent_set"`
+	ParentSet    *SearchParentSet `json:"parent_set,omitempty"`
+	Effects      []string         `json:"effects"`
+	Ingredients  []string         `json:"ingredients"`
+	StuffType    SearchStuffType  `json:"stuff_type"`
 }
 
 type SearchIndexedMount struct {
@@ -54,6 +65,7 @@ type SearchIndexedSet struct {
 	Level                 int             `json:"highest_equipment_level"`
 	ContainsCosmetics     bool            `json:"contains_cosmetics"`
 	ContainsCosmeticsOnly bool            `json:"contains_cosmetics_only"`
+	Effects               []string        `json:"effects"`
 	StuffType             SearchStuffType `json:"stuff_type"`
 }
 
@@ -386,26 +398,131 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 		item_category_Put(5, 764933) // Ausschmückungen
 	*/
 
-	multilangSearchIndexes := make(map[string]database.SearchIndexes)
-	var indexTasks []*meilisearch.TaskInfo
+	// create in-memory db
+	schema := GetMemDBSchema()
+
+	var err error
+	var db *memdb.MemDB
+	if db, err = memdb.NewMemDB(schema); err != nil {
+		log.Fatal(err)
+	}
+
+	txn := db.Txn(true)
+
+	// persistent elements are also in db. TODO does this update automatically?
+	persIt := config.PersistedElements.Entries.Iterator()
+	for persIt.Next() {
+		if err = txn.Insert("effect-condition-elements", &EffectConditionDbEntry{
+			Id:   persIt.Key().(int),
+			Name: persIt.Value().(string),
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// db prepare insertions
+	itemsTable := fmt.Sprintf("%s-all_items", utils.NextRedBlueVersionStr(version.MemDb))
+	setsTable := fmt.Sprintf("%s-sets", utils.NextRedBlueVersionStr(version.MemDb))
+	mountsTable := fmt.Sprintf("%s-mounts", utils.NextRedBlueVersionStr(version.MemDb))
+	recipesTable := fmt.Sprintf("%s-recipes", utils.NextRedBlueVersionStr(version.MemDb))
 
-	client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
+	for _, recipe := range *recipes {
+		recipeCt := recipe
+		if err = txn.Insert(recipesTable, &recipeCt); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	itemTypeIds := set.NewHashset[string](10, g.Equals[string], g.HashString)
+
+	itemCount := 0
+	for _, item := range *items {
+		itemCp := item
+		if itemCp.Type.CategoryId == 4 {
+			continue
+		}
+		insertCategoryTable := utils.CategoryIdMapping(itemCp.Type.CategoryId)
+
+		if err = txn.Insert(fmt.Sprintf("%s-%s", utils.NextRedBlueVersionStr(version.MemDb), insertCategoryTable), &itemCp); err != nil {
+			log.Fatal(err)
+		}
+
+		if err = txn.Insert(itemsTable, &itemCp); err != nil {
+			log.Fatal(err)
+		}
+		itemCount++
+
+		itemTypeIds.Put(strings.ToLower(strings.ReplaceAll(itemCp.Type.Name["en"], " ", "-")))
+	}
+
+	for id, itemTypeId := range itemTypeIds.Keys() {
+		if err = txn.Insert("item-type-ids", &ItemTypeId{
+			Id:     id,
+			EnName: itemTypeId,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, set := range *sets {
+		setCp := set
+		if err := txn.Insert(setsTable, &setCp); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, mount := range *mounts {
+		mountCp := mount
+		if err := txn.Insert(mountsTable, &mountCp); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	txn.Commit()
+	utils.SetMemDbEntries(utils.NextRedBlueVersionStr(version.MemDb), itemCount, len(*sets), len(*mounts))
+
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
 	defer client.Close()
 
+	if !client.IsHealthy() {
+		log.Warn("search engine is not reachable, serving search from memory until it is back", "host", config.MeiliHost)
+		database.SearchDegraded.Store(true)
+		return db, nil
+	}
+
+	multilangSearchIndexes, err := GenerateSearchIndexes(items, sets, recipes, mounts, utils.NextRedBlueVersionStr(version.Search), client)
+	if err != nil {
+		log.Error("could not build search indexes, serving search from memory until the search engine is back", "err", err)
+		database.SearchDegraded.Store(true)
+		return db, nil
+	}
+	database.SearchDegraded.Store(false)
+
+	return db, multilangSearchIndexes
+}
+
+// GenerateSearchIndexes (re)creates the items, sets and mounts indexes for every language under the given red/blue
+// prefix and fills them with documents. It does not touch the in-memory database.
+func GenerateSearchIndexes(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping.MappedMultilangSetUnity, recipes *[]mapping.MappedMultilangRecipe, mounts *[]mapping.MappedMultilangMount, redBlueVersion string, client meilisearch.ServiceManager) (map[string]database.SearchIndexes, error) {
+	multilangSearchIndexes := make(map[string]database.SearchIndexes)
+	var indexTasks []*meilisearch.TaskInfo
+
 	// generate all indexes with %version-%lang
-	//meiliPullInterval := 100 * time.Millisecond
 	updateTasks := make([]*meilisearch.TaskInfo, 0)
 
 	for _, lang := range config.Languages {
-		itemIndexUid := fmt.Sprintf("%s-all_items-%s", utils.NextRedBlueVersionStr(version.Search), lang)
-		setIndexUid := fmt.Sprintf("%s-sets-%s", utils.NextRedBlueVersionStr(version.Search), lang)
-		mountIndexUid := fmt.Sprintf("%s-mounts-%s", utils.NextRedBlueVersionStr(version.Search), lang)
+		itemIndexUid := fmt.Sprintf("%s-all_items-%s", redBlueVersion, lang)
+		setIndexUid := fmt.Sprintf("%s-sets-%s", redBlueVersion, lang)
+		mountIndexUid := fmt.Sprintf("%s-mounts-%s", redBlueVersion, lang)
 
-		createClearIndices([]string{
+		err := createClearIndices([]string{
 			itemIndexUid,
 			setIndexUid,
 			mountIndexUid,
 		}, client)
+		if err != nil {
+			return nil, err
+		}
 
 		// add filters and searchable attributes
 		// -- all items --
@@ -414,22 +531,35 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 			"super_type.name_id",
 			"type.name_id",
 			"level",
+			"level_bucket",
+			"has_parent_set",
+			"parent_set.id",
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		updateTasks = append(updateTasks, allItemsFilterTask)
 
+		// order matters, the attribute ranking rule prefers matches in earlier attributes
 		allItemsSearchableTask, err := allItemsIdx.UpdateSearchableAttributes(&[]string{
 			"name",
 			"type.name",
+			"parent_set.name",
+			"effects",
+			"ingredients",
 			"description",
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		updateTasks = append(updateTasks, allItemsSearchableTask)
 
+		allItemsRankingTask, err := allItemsIdx.UpdateRankingRules(&searchRankingRules)
+		if err != nil {
+			return nil, err
+		}
+		updateTasks = append(updateTasks, allItemsRankingTask)
+
 		// -- mounts --
 		mountsIdx := client.Index(mountIndexUid)
 		mountFilterTask, err := mountsIdx.UpdateFilterableAttributes(&[]string{
@@ -437,7 +567,7 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 			"family.id",
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		updateTasks = append(updateTasks, mountFilterTask)
 
@@ -446,7 +576,7 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 			"family.name",
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		updateTasks = append(updateTasks, mountSearchableTask)
 
@@ -458,116 +588,99 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 			"constains_cosmetics_only",
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		updateTasks = append(updateTasks, setFilterUpdateTask)
 
 		setSearchableTask, err := setsIdx.UpdateSearchableAttributes(&[]string{
 			"name",
+			"effects",
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		updateTasks = append(updateTasks, setSearchableTask)
 
+		setRankingTask, err := setsIdx.UpdateRankingRules(&searchRankingRules)
+		if err != nil {
+			return nil, err
+		}
+		updateTasks = append(updateTasks, setRankingTask)
+
 		multilangSearchIndexes[lang] = database.SearchIndexes{
 			AllItems: allItemsIdx,
 			Sets:     setsIdx,
 			Mounts:   mountsIdx,
 		}
+
+		dictionaryTasks, err := applySearchDictionary(multilangSearchIndexes[lang], lang)
+		if err != nil {
+			return nil, err
+		}
+		updateTasks = append(updateTasks, dictionaryTasks...)
 	}
 
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func(tasks []*meilisearch.TaskInfo, client meilisearch.ServiceManager) {
-		defer wg.Done()
-		waitForTasks(tasks, client, false)
-	}(updateTasks, client)
 	log.Info("waiting for all indexes to be updated")
-	wg.Wait()
-
-	// create in-memory db
-	schema := GetMemDBSchema()
-
-	var err error
-	var db *memdb.MemDB
-	if db, err = memdb.NewMemDB(schema); err != nil {
-		log.Fatal(err)
+	if err := waitForTasks(updateTasks, client, false); err != nil {
+		return nil, err
 	}
-
-	txn := db.Txn(true)
-
-	// persistent elements are also in db. TODO does this update automatically?
-	persIt := config.PersistedElements.Entries.Iterator()
-	for persIt.Next() {
-		if err = txn.Insert("effect-condition-elements", &EffectConditionDbEntry{
-			Id:   persIt.Key().(int),
-			Name: persIt.Value().(string),
-		}); err != nil {
-			log.Fatal(err)
-		}
+	for _, lang := range config.Languages {
+		markSearchDictionaryApplied(lang)
 	}
 
-	// db prepare insertions
 	maxBatchSize := 250
-	itemIndexBatch := make(map[string][]SearchIndexedItem)
-	itemsTable := fmt.Sprintf("%s-all_items", utils.NextRedBlueVersionStr(version.MemDb))
-	setsTable := fmt.Sprintf("%s-sets", utils.NextRedBlueVersionStr(version.MemDb))
-	mountsTable := fmt.Sprintf("%s-mounts", utils.NextRedBlueVersionStr(version.MemDb))
-	recipesTable := fmt.Sprintf("%s-recipes", utils.NextRedBlueVersionStr(version.MemDb))
 
-	for _, recipe := range *recipes {
-		recipeCt := recipe
-		if err = txn.Insert(recipesTable, &recipeCt); err != nil {
-			log.Fatal(err)
-		}
+	itemNames := make(map[int]map[string]string, len(*items))
+	for _, item := range *items {
+		itemNames[item.AnkamaId] = item.Name
+	}
+	recipesByResult := make(map[int]*mapping.MappedMultilangRecipe, len(*recipes))
+	for i := range *recipes {
+		recipesByResult[(*recipes)[i].ResultId] = &(*recipes)[i]
 	}
-
-	itemTypeIds := set.NewHashset[string](10, g.Equals[string], g.HashString)
 
 	// all items search
+	itemIndexBatch := make(map[string][]SearchIndexedItem)
 	for _, item := range *items {
-		itemCp := item
-		var insertCategoryTable string
-		if itemCp.Type.CategoryId == 4 {
+		if item.Type.CategoryId == 4 {
 			continue
 		}
-		insertCategoryTable = utils.CategoryIdMapping(itemCp.Type.CategoryId)
-
-		if err = txn.Insert(fmt.Sprintf("%s-%s", utils.NextRedBlueVersionStr(version.MemDb), insertCategoryTable), &itemCp); err != nil {
-			log.Fatal(err)
-		}
-
-		if err = txn.Insert(itemsTable, &itemCp); err != nil {
-			log.Fatal(err)
-		}
+		insertCategoryTable := utils.CategoryIdMapping(item.Type.CategoryId)
 
 		for _, lang := range config.Languages {
-			enTypeId := strings.ToLower(strings.ReplaceAll(itemCp.Type.Name["en"], " ", "-"))
+			enTypeId := strings.ToLower(strings.ReplaceAll(item.Type.Name["en"], " ", "-"))
 			object := SearchIndexedItem{
-				Name:        itemCp.Name[lang],
-				Id:          itemCp.AnkamaId,
-				Description: itemCp.Description[lang],
+				Name:        item.Name[lang],
+				Id:          item.AnkamaId,
+				Description: item.Description[lang],
 				SuperType: SearchStuffType{
 					NameId: insertCategoryTable,
 				},
 				Type: SearchType{
-					Name:   strings.ToLower(itemCp.Type.Name[lang]),
+					Name:   strings.ToLower(item.Type.Name[lang]),
 					NameId: enTypeId,
 				},
-				Level: itemCp.Level,
+				Level:        item.Level,
+				LevelBucket:  levelBucket(item.Level),
+				HasParentSet: item.HasParentSet,
+				Effects:      searchEffectNames(item.Effects, lang),
+				Ingredients:  searchIngredientNames(recipesByResult[item.AnkamaId], itemNames, lang),
 				StuffType: SearchStuffType{
 					NameId: fmt.Sprintf("items-%s", insertCategoryTable),
 				},
 			}
-
-			itemTypeIds.Put(enTypeId)
+			if item.HasParentSet {
+				object.ParentSet = &SearchParentSet{
+					Id:   item.ParentSet.Id,
+					Name: item.ParentSet.Name[lang],
+				}
+			}
 
 			itemIndexBatch[lang] = append(itemIndexBatch[lang], object)
 			if len(itemIndexBatch[lang]) >= maxBatchSize {
-				var taskInfo *meilisearch.TaskInfo
-				if taskInfo, err = multilangSearchIndexes[lang].AllItems.AddDocuments(itemIndexBatch[lang]); err != nil {
-					log.Fatal(err)
+				taskInfo, err := multilangSearchIndexes[lang].AllItems.AddDocuments(itemIndexBatch[lang])
+				if err != nil {
+					return nil, err
 				}
 				indexTasks = append(indexTasks, taskInfo)
 				itemIndexBatch[lang] = make([]SearchIndexedItem, 0)
@@ -578,39 +691,26 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 	// leftover items
 	for _, lang := range config.Languages {
 		if len(itemIndexBatch[lang]) > 0 {
-			var taskInfo *meilisearch.TaskInfo
-			if taskInfo, err = multilangSearchIndexes[lang].AllItems.AddDocuments(itemIndexBatch[lang]); err != nil {
-				log.Fatal(err)
+			taskInfo, err := multilangSearchIndexes[lang].AllItems.AddDocuments(itemIndexBatch[lang])
+			if err != nil {
+				return nil, err
 			}
 			indexTasks = append(indexTasks, taskInfo)
 			itemIndexBatch[lang] = make([]SearchIndexedItem, 0)
 		}
 	}
 
-	for id, itemTypeId := range itemTypeIds.Keys() {
-		if err = txn.Insert("item-type-ids", &ItemTypeId{
-			Id:     id,
-			EnName: itemTypeId,
-		}); err != nil {
-			log.Fatal(err)
-		}
-	}
-
 	// sets
 	setIndexBatch := make(map[string][]SearchIndexedSet)
 	for _, set := range *sets {
-		setCp := set
-		if err := txn.Insert(setsTable, &setCp); err != nil {
-			log.Fatal(err)
-		}
-
 		for _, lang := range config.Languages {
 			object := SearchIndexedSet{
-				Name:                  setCp.Name[lang],
-				Id:                    setCp.AnkamaId,
-				Level:                 setCp.Level,
-				ContainsCosmetics:     setCp.ContainsCosmetics,
-				ContainsCosmeticsOnly: setCp.ContainsCosmeticsOnly,
+				Name:                  set.Name[lang],
+				Id:                    set.AnkamaId,
+				Level:                 set.Level,
+				ContainsCosmetics:     set.ContainsCosmetics,
+				ContainsCosmeticsOnly: set.ContainsCosmeticsOnly,
+				Effects:               searchSetEffectNames(set.Effects, lang),
 				StuffType: SearchStuffType{
 					NameId: "sets",
 				},
@@ -620,7 +720,7 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 			if len(setIndexBatch[lang]) >= maxBatchSize {
 				taskInfo, err := multilangSearchIndexes[lang].Sets.AddDocuments(setIndexBatch[lang])
 				if err != nil {
-					log.Fatal(err)
+					return nil, err
 				}
 				indexTasks = append(indexTasks, taskInfo)
 				setIndexBatch[lang] = nil
@@ -631,9 +731,9 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 	// leftover sets
 	for _, lang := range config.Languages {
 		if len(setIndexBatch[lang]) > 0 {
-			var taskInfo *meilisearch.TaskInfo
-			if taskInfo, err = multilangSearchIndexes[lang].AllItems.AddDocuments(setIndexBatch[lang]); err != nil {
-				log.Fatal(err)
+			taskInfo, err := multilangSearchIndexes[lang].Sets.AddDocuments(setIndexBatch[lang])
+			if err != nil {
+				return nil, err
 			}
 			indexTasks = append(indexTasks, taskInfo)
 			setIndexBatch[lang] = make([]SearchIndexedSet, 0)
@@ -643,18 +743,13 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 	// mounts
 	mountIndexBatch := make(map[string][]SearchIndexedMount)
 	for _, mount := range *mounts {
-		mountCp := mount
-		if err := txn.Insert(mountsTable, &mountCp); err != nil {
-			log.Fatal(err)
-		}
-
 		for _, lang := range config.Languages {
 			object := SearchIndexedMount{
-				Name: mountCp.Name[lang],
-				Id:   mountCp.AnkamaId,
+				Name: mount.Name[lang],
+				Id:   mount.AnkamaId,
 				Family: ApiType{
-					Name: strings.ToLower(mountCp.FamilyName[lang]),
-					Id:   mountCp.FamilyId,
+					Name: strings.ToLower(mount.FamilyName[lang]),
+					Id:   mount.FamilyId,
 				},
 				StuffType: SearchStuffType{
 					NameId: "mounts",
@@ -665,7 +760,7 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 			if len(mountIndexBatch[lang]) >= maxBatchSize {
 				taskInfo, err := multilangSearchIndexes[lang].Mounts.AddDocuments(mountIndexBatch[lang])
 				if err != nil {
-					log.Fatal(err)
+					return nil, err
 				}
 				indexTasks = append(indexTasks, taskInfo)
 				mountIndexBatch[lang] = nil
@@ -676,30 +771,79 @@ func GenerateDatabase(items *[]mapping.MappedMultilangItemUnity, sets *[]mapping
 	// leftover mounts
 	for _, lang := range config.Languages {
 		if len(mountIndexBatch[lang]) > 0 {
-			var taskInfo *meilisearch.TaskInfo
-			if taskInfo, err = multilangSearchIndexes[lang].AllItems.AddDocuments(mountIndexBatch[lang]); err != nil {
-				log.Fatal(err)
+			taskInfo, err := multilangSearchIndexes[lang].Mounts.AddDocuments(mountIndexBatch[lang])
+			if err != nil {
+				return nil, err
 			}
 			indexTasks = append(indexTasks, taskInfo)
 			mountIndexBatch[lang] = make([]SearchIndexedMount, 0)
 		}
 	}
 
-	txn.Commit()
-
 	// wait for all indexing tasks to finish
-	wg.Add(1)
-	go func(tasks []*meilisearch.TaskInfo, client meilisearch.ServiceManager) {
-		defer wg.Done()
-		waitForTasks(tasks, client, false)
-	}(indexTasks, client)
 	log.Info("waiting for all documents to be indexed")
-	wg.Wait()
+	if err := waitForTasks(indexTasks, client, false); err != nil {
+		return nil, err
+	}
 
-	return db, multilangSearchIndexes
+	return multilangSearchIndexes, nil
+}
+
+// searchRankingRules is the Meilisearch default with attribute moved before proximity, so a hit in the name beats a
+// hit in effects or descriptions even when the words are further apart.
+var searchRankingRules = []string{
+	"words",
+	"typo",
+	"attribute",
+	"proximity",
+	"sort",
+	"exactness",
+}
+
+// searchEffectNames lists the distinct effect type names like "AP" or "Wisdom" of an item in one language.
+func searchEffectNames(effects []mapping.MappedMultilangEffect, lang string) []string {
+	names := make([]string, 0, len(effects))
+	seen := make(map[string]bool, len(effects))
+	for _, effect := range effects {
+		name := effect.Type[lang]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func searchSetEffectNames(effects map[int][]mapping.MappedMultilangEffect, lang string) []string {
+	itemCounts := make([]int, 0, len(effects))
+	for itemCount := range effects {
+		itemCounts = append(itemCounts, itemCount)
+	}
+	sort.Ints(itemCounts)
+
+	var all []mapping.MappedMultilangEffect
+	for _, itemCount := range itemCounts {
+		all = append(all, effects[itemCount]...)
+	}
+	return searchEffectNames(all, lang)
 }
 
-func createClearIndices(indexNames []string, client meilisearch.ServiceManager) {
+func searchIngredientNames(recipe *mapping.MappedMultilangRecipe, itemNames map[int]map[string]string, lang string) []string {
+	if recipe == nil {
+		return []string{}
+	}
+
+	names := make([]string, 0, len(recipe.Entries))
+	for _, entry := range recipe.Entries {
+		if name, ok := itemNames[entry.ItemId][lang]; ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func createClearIndices(indexNames []string, client meilisearch.ServiceManager) error {
 	for _, indexName := range indexNames {
 		index, err := client.GetIndex(indexName)
 		if err != nil {
@@ -710,30 +854,29 @@ func createClearIndices(indexNames []string, client meilisearch.ServiceManager)
 					PrimaryKey: "id",
 				})
 				if err != nil {
-					log.Error("Error while creating index in meili", "err", err)
-					return
+					return fmt.Errorf("could not create index %s: %w", indexName, err)
 				}
 
 				task, err := client.WaitForTask(taskInfo.TaskUID, 100*time.Millisecond)
 				if err != nil {
-					log.Error("Error while waiting index creation at meili", "err", err)
-					return
+					return fmt.Errorf("could not wait for index creation %s: %w", indexName, err)
 				}
 
 				if task.Status != meilisearch.TaskStatusSucceeded {
 					log.Error("Meili", "status", task.Status, "message", task.Error.Message)
 				}
 			} else {
-				log.Error("Error while getting index in meili", "err", err)
-				return
+				return fmt.Errorf("could not get index %s: %w", indexName, err)
 			}
 		} else { // clear index and start over
 			log.Info("index exists, clearing", "index", indexName)
 			delTask, err := index.DeleteAllDocuments()
+			if err != nil {
+				return fmt.Errorf("could not clear index %s: %w", indexName, err)
+			}
 			task, err := client.WaitForTask(delTask.TaskUID, 100*time.Millisecond)
 			if err != nil {
-				log.Error("Error while waiting index creation at meili", "err", err)
-				return
+				return fmt.Errorf("could not wait for index clearing %s: %w", indexName, err)
 			}
 
 			if task.Status != meilisearch.TaskStatusSucceeded {
@@ -741,14 +884,17 @@ func createClearIndices(indexNames []string, client meilisearch.ServiceManager)
 			}
 		}
 	}
+
+	return nil
 }
 
-func waitForTasks(tasks []*meilisearch.TaskInfo, client meilisearch.ServiceManager, ignoreExists bool) {
+func waitForTasks(tasks []*meilisearch.TaskInfo, client meilisearch.ServiceManager, ignoreExists bool) error {
 	if len(tasks) == 0 {
-		return
+		return nil
 	}
 	wg := sync.WaitGroup{}
 	semap := make(chan struct{}, runtime.NumCPU()*2)
+	errs := make(chan error, len(tasks))
 	for _, task := range tasks {
 		wg.Add(1)
 		go func(taskInfo *meilisearch.TaskInfo, client meilisearch.ServiceManager) {
@@ -761,7 +907,8 @@ func waitForTasks(tasks []*meilisearch.TaskInfo, client meilisearch.ServiceManag
 
 			task, err := client.WaitForTask(taskInfo.TaskUID, 100*time.Millisecond)
 			if err != nil {
-				log.Fatal(err)
+				errs <- err
+				return
 			}
 
 			if ignoreExists && task.Status == meilisearch.TaskStatusFailed && !strings.Contains(task.Error.Message, "already exists") {
@@ -774,4 +921,34 @@ func waitForTasks(tasks []*meilisearch.TaskInfo, client meilisearch.ServiceManag
 		}(task, client)
 	}
 	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+func deleteSearchIndexes(redBlueVersion string) error {
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
+	defer client.Close()
+
+	for _, lang := range config.Languages {
+		for _, indexType := range []string{"all_items", "sets", "mounts"} {
+			indexUid := fmt.Sprintf("%s-%s-%s", redBlueVersion, indexType, lang)
+			deleteTask, err := client.DeleteIndex(indexUid)
+			if err != nil {
+				return fmt.Errorf("could not delete index %s: %w", indexUid, err)
+			}
+
+			task, err := client.WaitForTask(deleteTask.TaskUID, 500*time.Millisecond)
+			if err != nil {
+				return fmt.Errorf("could not wait for deletion of index %s: %w", indexUid, err)
+			}
+
+			// a generation that was never built has nothing to delete
+			if task.Status == meilisearch.TaskStatusFailed && task.Error.Code != "index_not_found" {
+				return fmt.Errorf("deleting index %s failed: %s", indexUid, task.Error.Message)
+			}
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	mapping "github.com/dofusdude/dodumap"
+)
+
+func testEffect(en string, fr string) mapping.MappedMultilangEffect {
+	return mapping.MappedMultilangEffect{Type: map[string]string{"en": en, "fr": fr}}
+}
+
+func TestSearchEffectNames(t *testing.T) {
+	effects := []mapping.MappedMultilangEffect{
+		testEffect("Vitality", "Vitalité"),
+		testEffect("Strength", ""),
+		testEffect("Vitality", "Vitalité"),
+		testEffect("", "Sagesse"),
+	}
+
+	tests := []struct {
+		lang     string
+		expected []string
+	}{
+		{"en", []string{"Vitality", "Strength"}},
+		{"fr", []string{"Vitalité", "Sagesse"}},
+		{"de", []string{}},
+	}
+	for _, test := range tests {
+		if names := searchEffectNames(effects, test.lang); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.lang, test.expected, names)
+		}
+	}
+}
+
+func TestSearchSetEffectNames(t *testing.T) {
+	effects := map[int][]mapping.MappedMultilangEffect{
+		4: {testEffect("Wisdom", "Sagesse"), testEffect("Vitality", "Vitalité")},
+		2: {testEffect("Vitality", "Vitalité")},
+		3: {testEffect("Agility", "Agilité")},
+	}
+
+	// ordered by the number of set items, every effect once
+	expected := []string{"Vitality", "Agility", "Wisdom"}
+	if names := searchSetEffectNames(effects, "en"); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestSearchIngredientNames(t *testing.T) {
+	itemNames := map[int]map[string]string{
+		289: {"en": "Wheat", "fr": "Blé"},
+		303: {"en": "Ash Wood", "fr": ""},
+	}
+	recipe := &mapping.MappedMultilangRecipe{Entries: []mapping.MappedMultilangRecipeEntry{
+		{ItemId: 289, Quantity: 3},
+		{ItemId: 303, Quantity: 1},
+		{ItemId: 999, Quantity: 2}, // not in the item list
+	}}
+
+	tests := []struct {
+		recipe   *mapping.MappedMultilangRecipe
+		lang     string
+		expected []string
+	}{
+		{recipe, "en", []string{"Wheat", "Ash Wood"}},
+		{recipe, "fr", []string{"Blé"}},
+		{nil, "en", []string{}},
+	}
+	for _, test := range tests {
+		if names := searchIngredientNames(test.recipe, itemNames, test.lang); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.lang, test.expected, names)
+		}
+	}
+}
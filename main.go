@@ -2,12 +2,11 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,13 +20,10 @@ import (
 	"github.com/dofusdude/doduapi/ui"
 	"github.com/dofusdude/doduapi/utils"
 	"github.com/hashicorp/go-memdb"
-	"github.com/meilisearch/meilisearch-go"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stelzo/migrate/v4"
-	"github.com/stelzo/migrate/v4/database/sqlite3"
-	"github.com/stelzo/migrate/v4/source/file"
 )
 
 var (
@@ -39,6 +35,7 @@ var (
 	httpDataServer     *http.Server
 	httpMetricsServer  *http.Server
 	UpdateChan         chan utils.GameVersion
+	UpdateSearchIndex  chan map[string]database.SearchIndexes
 )
 
 var currentWd string
@@ -53,12 +50,37 @@ func ReadEnvs() {
 	viper.SetDefault("MEILI_MASTER_KEY", "masterKey")
 	viper.SetDefault("MEILI_PROTOCOL", "http")
 	viper.SetDefault("MEILI_HOST", "127.0.0.1")
+	viper.SetDefault("MEILI_RECONNECT_INTERVAL", "30s")
+	viper.SetDefault("SEARCH_DICTIONARY_DIR", "")
+	viper.SetDefault("SEARCH_DICTIONARY_RELOAD_INTERVAL", "1m")
 	viper.SetDefault("PROMETHEUS", "false")
 	viper.SetDefault("FILESERVER", "true")
 	viper.SetDefault("ALMANAX_MAX_LOOKAHEAD_DAYS", 365)
 	viper.SetDefault("ALMANAX_DEFAULT_LOOKAHEAD_DAYS", 6)
+	viper.SetDefault("ALMANAX_TIMEZONE", "Europe/Paris")
+	viper.SetDefault("ALMANAX_RESET_TIME", "00:00")
 	viper.SetDefault("IS_BETA", "false")
 	viper.SetDefault("UPDATE_HOOK_TOKEN", "")
+	viper.SetDefault("UPDATE_HOOK_SECRET", "")
+	viper.SetDefault("RELEASE_POLL_INTERVAL", "0")
+	viper.SetDefault("GITHUB_TOKEN", "")
+	viper.SetDefault("RATE_LIMIT", "false")
+	viper.SetDefault("RATE_LIMIT_SEARCH", "60/1m")
+	viper.SetDefault("RATE_LIMIT_ALL", "10/1m")
+	viper.SetDefault("RATE_LIMIT_SINGLE", "300/1m")
+	viper.SetDefault("RATE_LIMIT_DEFAULT", "120/1m")
+	viper.SetDefault("RATE_LIMIT_AUTH_FAIL", "10/1m")
+	viper.SetDefault("API_KEYS", "")
+	viper.SetDefault("TRUST_PROXY_HEADERS", "false")
+	viper.SetDefault("CORS_ORIGINS", "*")
+	viper.SetDefault("CORS_MAX_AGE", "1h")
+	viper.SetDefault("ADMIN_CORS_ORIGINS", "")
+	viper.SetDefault("ADMIN_CORS_CREDENTIALS", "false")
+	viper.SetDefault("WEBHOOK_TOKEN", "")
+	viper.SetDefault("ADMIN_TOKENS", "")
+	viper.SetDefault("CONFIG_FILE", "")
+	viper.SetDefault("WEBHOOK_INTERVAL", "1m")
+	viper.SetDefault("MIGRATE_ON_START", "false")
 	viper.SetDefault("DOFUS_VERSION", "")
 	viper.SetDefault("LOG_LEVEL", "warn")
 
@@ -71,6 +93,14 @@ func ReadEnvs() {
 
 	viper.AutomaticEnv()
 
+	if configFile := viper.GetString("CONFIG_FILE"); configFile != "" {
+		viper.SetConfigFile(configFile)
+		viper.SetConfigType("env")
+		if err = viper.ReadInConfig(); err != nil {
+			log.Fatal("could not read CONFIG_FILE", "err", err)
+		}
+	}
+
 	config.IsBeta = viper.GetBool("IS_BETA")
 	var betaStr string
 	if config.IsBeta {
@@ -79,36 +109,27 @@ func ReadEnvs() {
 		betaStr = "main"
 	}
 
-	config.AlmanaxMaxLookAhead = viper.GetInt("ALMANAX_MAX_LOOKAHEAD_DAYS")
-	config.AlmanaxDefaultLookAhead = viper.GetInt("ALMANAX_DEFAULT_LOOKAHEAD_DAYS")
+	config.AlmanaxLocation, err = time.LoadLocation(viper.GetString("ALMANAX_TIMEZONE"))
+	if err != nil {
+		log.Fatal("invalid ALMANAX_TIMEZONE", "err", err)
+	}
+	config.AlmanaxResetTime, err = almanax.ParseResetTime(viper.GetString("ALMANAX_RESET_TIME"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config.GithubToken = viper.GetString("GITHUB_TOKEN")
+	config.ReleasePollInterval = viper.GetDuration("RELEASE_POLL_INTERVAL")
 
 	dofusVersion := viper.GetString("DOFUS_VERSION")
 	if dofusVersion == "" {
-		releaseApiResponse, err := http.Get(fmt.Sprintf("https://api.github.com/repos/dofusdude/dofus3-%s/releases/latest", betaStr))
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		releaseApiResponseBody, err := io.ReadAll(releaseApiResponse.Body)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		var v map[string]interface{}
-		err = json.Unmarshal(releaseApiResponseBody, &v)
+		config.DofusVersion, err = NewGithubReleaseSource(betaStr).LatestRelease(context.Background())
 		if err != nil {
 			log.Fatal(err)
 		}
-
-		config.DofusVersion = v["name"].(string)
 	} else {
 		config.DofusVersion = dofusVersion
 	}
-	parsedLevel, err := log.ParseLevel(viper.GetString("LOG_LEVEL"))
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.SetLevel(parsedLevel)
 
 	dofus3Prefix := ""
 	if strings.HasPrefix(config.DofusVersion, "3") {
@@ -124,13 +145,103 @@ func ReadEnvs() {
 	config.ApiPort = viper.GetString("API_PORT")
 	config.MeiliKey = viper.GetString("MEILI_MASTER_KEY")
 	config.MeiliHost = fmt.Sprintf("%s://%s:%s", viper.GetString("MEILI_PROTOCOL"), viper.GetString("MEILI_HOST"), viper.GetString("MEILI_PORT"))
+	config.MeiliReconnectInterval = readInterval("MEILI_RECONNECT_INTERVAL")
 	config.PrometheusEnabled = viper.GetBool("PROMETHEUS")
 	config.PublishFileServer = viper.GetBool("FILESERVER")
 	config.UpdateHookToken = viper.GetString("UPDATE_HOOK_TOKEN")
+	config.WebhookInterval = readInterval("WEBHOOK_INTERVAL")
+	config.MigrateOnStart = viper.GetBool("MIGRATE_ON_START")
 	config.DockerMountDataPath = viper.GetString("DIR")
+	config.SearchDictionaryDir = viper.GetString("SEARCH_DICTIONARY_DIR")
+	if config.SearchDictionaryDir == "" {
+		config.SearchDictionaryDir = filepath.Join(config.DockerMountDataPath, "data", "search")
+	}
+	config.SearchDictionaryReload = readInterval("SEARCH_DICTIONARY_RELOAD_INTERVAL")
+	config.TrustProxyHeaders = viper.GetBool("TRUST_PROXY_HEADERS")
+	config.CorsOrigins = splitList(viper.GetString("CORS_ORIGINS"))
+	config.CorsMaxAge = viper.GetDuration("CORS_MAX_AGE")
+	config.AdminCorsOrigins = splitList(viper.GetString("ADMIN_CORS_ORIGINS"))
+	config.AdminCorsCredentials = viper.GetBool("ADMIN_CORS_CREDENTIALS")
+	config.RateLimitEnabled = viper.GetBool("RATE_LIMIT")
+	config.RateLimitQuotas = make(map[string]config.RateLimitQuota)
+	for _, class := range []string{rateLimitSearch, rateLimitAll, rateLimitSingle, rateLimitDefault, rateLimitAuthFail} {
+		config.RateLimitQuotas[class], err = parseRateLimitQuota(viper.GetString("RATE_LIMIT_" + strings.ToUpper(class)))
+		if err != nil {
+			log.Fatal("invalid RATE_LIMIT_"+strings.ToUpper(class), "err", err)
+		}
+	}
+
+	if err = applyRuntimeConfig(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// splitList reads comma separated settings and drops empty entries.
+func splitList(raw string) []string {
+	list := make([]string, 0)
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// readInterval reads a duration setting for a background loop, where 0 or less disables the loop. viper would read
+// a typo as 0 too, so those stop the start instead.
+func readInterval(key string) time.Duration {
+	raw := strings.TrimSpace(viper.GetString(key))
+	if raw == "" || raw == "0" {
+		return 0
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Fatal("invalid "+key, "err", err)
+	}
+	return interval
+}
+
+// applyRuntimeConfig sets everything that can change while the API runs. Everything else needs a restart.
+func applyRuntimeConfig() error {
+	parsedLevel, err := log.ParseLevel(viper.GetString("LOG_LEVEL"))
+	if err != nil {
+		return err
+	}
+
+	tokens, err := parseAdminTokens(viper.GetString("ADMIN_TOKENS"))
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseApiKeys(viper.GetString("API_KEYS"))
+	if err != nil {
+		return err
+	}
+
+	log.SetLevel(parsedLevel)
+	adminTokens.Store(&tokens)
+	apiKeys.Store(&keys)
+	config.SetRuntimeConfig(config.Runtime{
+		AlmanaxMaxLookAhead:     viper.GetInt("ALMANAX_MAX_LOOKAHEAD_DAYS"),
+		AlmanaxDefaultLookAhead: viper.GetInt("ALMANAX_DEFAULT_LOOKAHEAD_DAYS"),
+		UpdateHookSecret:        viper.GetString("UPDATE_HOOK_SECRET"),
+		WebhookToken:            viper.GetString("WEBHOOK_TOKEN"),
+	})
+	return nil
+}
+
+// reloadConfig reads CONFIG_FILE again, if there is one, and applies the runtime settings. Environment variables
+// still take precedence over the file.
+func reloadConfig() error {
+	if viper.ConfigFileUsed() != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			return err
+		}
+	}
+	return applyRuntimeConfig()
 }
 
-func AutoUpdate(version *database.VersionT, updateHook chan utils.GameVersion, updateDb chan *memdb.MemDB, updateSearchIndex chan map[string]database.SearchIndexes) {
+func AutoUpdate(version *database.VersionT, almanaxRepo *database.Repository, updateHook chan utils.GameVersion, updateDb chan *memdb.MemDB, updateSearchIndex chan map[string]database.SearchIndexes) {
 	for {
 		select {
 		case gameVersion, ok := <-updateHook:
@@ -141,6 +252,7 @@ func AutoUpdate(version *database.VersionT, updateHook chan utils.GameVersion, u
 			var err error
 			updateStart := time.Now()
 			log.Print("Initialize update...")
+			searchIndexMutex.Lock()
 			db, idx := IndexApiData(version)
 
 			// send data to main thread
@@ -173,85 +285,45 @@ func AutoUpdate(version *database.VersionT, updateHook chan utils.GameVersion, u
 				log.Fatal(err)
 			}
 			delOldTxn.Commit()
+			utils.SetMemDbEntries(utils.NextRedBlueVersionStr(version.MemDb), 0, 0, 0)
 
 			// ----
-			updateSearchIndex <- idx
+			// without a search engine, the new generation was not built and the old indexes stay active until
+			// WatchSearchEngine rebuilds them
+			searchBuilt := !database.SearchDegraded.Load()
+			if searchBuilt {
+				updateSearchIndex <- idx
+			}
 
 			if !config.SkipAlmanax {
-				err = almanax.GatherAlmanaxData(false, true) // headless true since we want the log output
+				err = almanax.GatherAlmanaxData(almanaxRepo, gameVersion.Version, false, true) // headless true since we want the log output
 				if err != nil {
 					log.Fatal(err) // TODO notify on error, not just hard exit since we want high availability
 				}
 			}
 
-			nowOldRedBlueVersion := utils.CurrentRedBlueVersionStr(version.Search)
-
-			log.Info("atomic version switch")
-			version.Search = !version.Search
-
-			client := meilisearch.New(config.MeiliHost, meilisearch.WithAPIKey(config.MeiliKey))
-			defer client.Close()
-
-			for _, lang := range config.Languages {
-				nowOldItemIndexUid := fmt.Sprintf("%s-all_items-%s", nowOldRedBlueVersion, lang)
-				nowOldSetIndexUid := fmt.Sprintf("%s-sets-%s", nowOldRedBlueVersion, lang)
-				nowOldMountIndexUid := fmt.Sprintf("%s-mounts-%s", nowOldRedBlueVersion, lang)
-
-				itemDeleteTask, err := client.DeleteIndex(nowOldItemIndexUid)
-				if err != nil {
-					log.Error("Error while deleting old item index.", "err", err)
-					return
-				}
-				task, err := client.WaitForTask(itemDeleteTask.TaskUID, 500*time.Millisecond)
-				if err != nil {
-					log.Error("Error while deleting old item index.", "err", err)
-					return
-				}
-
-				if task.Status == "failed" {
-					log.Error("Error while deleting old item index.", "err", task.Error)
-					return
-				}
-
-				setDeletionTask, err := client.DeleteIndex(nowOldSetIndexUid)
-				if err != nil {
-					log.Error("Error while deleting old set index.", "err", err)
-					return
-				}
-				task, err = client.WaitForTask(setDeletionTask.TaskUID, 500*time.Millisecond)
-				if err != nil {
-					log.Error("Error while deleting old set index.", "err", err)
-					return
-				}
-
-				if task.Status == "failed" {
-					log.Error("Error while deleting old set index.", "err", task.Error)
-					return
-				}
+			if searchBuilt {
+				nowOldRedBlueVersion := utils.CurrentRedBlueVersionStr(version.Search)
 
-				mountDeletionTask, err := client.DeleteIndex(nowOldMountIndexUid)
-				if err != nil {
-					log.Error("Error while deleting old mount index.", "err", err)
-					return
-				}
-				task, err = client.WaitForTask(mountDeletionTask.TaskUID, 500*time.Millisecond)
-				if err != nil {
-					log.Error("Error while deleting old mount index.", "err", err)
-					return
-				}
+				log.Info("atomic version switch")
+				version.Search = !version.Search
 
-				if task.Status == "failed" {
-					log.Error("Error while deleting old mount index.", "err", task.Error)
-					return
+				if err = deleteSearchIndexes(nowOldRedBlueVersion); err != nil {
+					log.Error("Error while deleting old search indexes.", "err", err)
 				}
-
+			} else {
+				log.Warn("search engine unavailable, keeping old search indexes until it is back")
 			}
+			searchIndexMutex.Unlock()
 			log.Info("deleted old in-memory data")
 			log.Print("Updated", "s", time.Since(updateStart).Seconds())
+			utils.UpdateDuration.Set(time.Since(updateStart).Seconds())
+			utils.LastSuccessfulUpdate.SetToCurrentTime()
 
 			// update version info for API meta endpoint
 			gameVersion.UpdateStamp = time.Now()
-			config.CurrentVersion = gameVersion
+			config.SetCurrentVersion(gameVersion)
+			pendingUpdates.release(gameVersion.Version)
 		}
 	}
 }
@@ -296,37 +368,142 @@ var (
 		Long:  `Command to upgrade database`,
 		Run:   migrateUp,
 	}
+
+	migrateStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "show applied and pending migrations",
+		Long:  `Command to show the database version and the migrations that still need to run`,
+		Run:   migrateStatus,
+	}
+
+	migrateGotoCmd = &cobra.Command{
+		Use:   "goto <version>",
+		Short: "migrate up or down to a version",
+		Long:  `Command to migrate the database up or down to the given version`,
+		Args:  cobra.ExactArgs(1),
+		Run:   migrateGoto,
+	}
+
+	migrateForceCmd = &cobra.Command{
+		Use:   "force <version>",
+		Short: "set the version without running migrations",
+		Long:  `Command to set the database version and clear the dirty flag after fixing a failed migration by hand`,
+		Args:  cobra.ExactArgs(1),
+		Run:   migrateForce,
+	}
+
+	almanaxCmd = &cobra.Command{
+		Use:   "almanax",
+		Short: "Maintain the Almanax database.",
+	}
+
+	almanaxBackfillCmd = &cobra.Command{
+		Use:   "backfill [versions...]",
+		Short: "store past days from older data releases",
+		Long:  `Command to fill the Almanax history with the past days of the given data releases, dated by their publishing time and in any order. Without versions, all releases are used.`,
+		Run:   almanaxBackfill,
+	}
+
+	almanaxIngestCmd = &cobra.Command{
+		Use:   "ingest [version]",
+		Short: "store the next year from a data release",
+		Long:  `Command to store the next Almanax year from the given data release, DOFUS_VERSION by default. Use --dry-run to only list the days that would change.`,
+		Args:  cobra.MaximumNArgs(1),
+		Run:   almanaxIngest,
+	}
 )
 
-func migrateUp(cmd *cobra.Command, args []string) {
+func almanaxIngest(cmd *cobra.Command, args []string) {
 	dbdir, err := cmd.Flags().GetString("persistent-dir")
 	if err != nil {
 		log.Fatal(err)
 	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ReadEnvs()
 	config.DbDir = dbdir
 
-	if _, err := os.Stat(dbdir); os.IsNotExist(err) {
-		os.Mkdir(dbdir, 0755)
+	version := config.DofusVersion
+	if len(args) == 1 {
+		version = args[0]
+	}
+
+	repo, err := database.OpenRepository(context.Background(), dbdir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer repo.Close()
+
+	if err = repo.CheckSchema(); err != nil {
+		log.Fatal(err)
+	}
+
+	summary, err := almanax.IngestAlmanax(repo, version, dryRun)
+	if err != nil {
+		log.Fatalf("almanax ingest error: %v \n", err)
+	}
+
+	for _, change := range summary.Changes {
+		fmt.Println(change.Date, change.Action)
+	}
+	log.Print("Almanax ingest done", "version", version, "dry-run", dryRun, "inserted", summary.Inserted, "updated", summary.Updated, "unchanged", summary.Unchanged)
+}
+
+func almanaxBackfill(cmd *cobra.Command, args []string) {
+	dbdir, err := cmd.Flags().GetString("persistent-dir")
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.DbDir = dbdir
+
+	repo, err := database.OpenRepository(context.Background(), dbdir)
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer repo.Close()
 
-	database := database.NewDatabaseRepository(context.Background(), dbdir)
+	if err = repo.CheckSchema(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err = almanax.BackfillAlmanaxHistory(repo, args); err != nil {
+		log.Fatalf("almanax backfill error: %v \n", err)
+	}
+	log.Print("Almanax backfill done with success")
+}
 
-	dbDriver, err := sqlite3.WithInstance(database.Db, &sqlite3.Config{})
+// openMigrator opens the database of the persistent-dir flag with the embedded migrations.
+func openMigrator(cmd *cobra.Command) (*migrate.Migrate, *database.Repository) {
+	dbdir, err := cmd.Flags().GetString("persistent-dir")
 	if err != nil {
-		log.Fatalf("instance error: %v \n", err)
+		log.Fatal(err)
+	}
+	config.DbDir = dbdir
+
+	if _, err := os.Stat(dbdir); os.IsNotExist(err) {
+		os.Mkdir(dbdir, 0755)
 	}
 
-	fileSource, err := (&file.File{}).Open("file://migrations")
+	repo, err := database.OpenRepository(context.Background(), dbdir)
 	if err != nil {
-		log.Fatalf("opening file error: %v \n", err)
+		log.Fatalf("database error: %v \n", err)
 	}
 
-	m, err := migrate.NewWithInstance("file", fileSource, "myDB", dbDriver)
+	m, err := repo.Migrator()
 	if err != nil {
 		log.Fatalf("migrate error: %v \n", err)
 	}
 
-	if err = m.Up(); err != nil {
+	return m, repo
+}
+
+func migrateUp(cmd *cobra.Command, args []string) {
+	m, repo := openMigrator(cmd)
+	defer repo.Close()
+
+	if err := m.Up(); err != nil {
 		if err == migrate.ErrNoChange {
 			log.Print("No change detected in migrations")
 		} else {
@@ -338,34 +515,81 @@ func migrateUp(cmd *cobra.Command, args []string) {
 }
 
 func migrateDown(cmd *cobra.Command, args []string) {
-	dbdir, err := cmd.Flags().GetString("persistent-dir")
-	if err != nil {
-		log.Fatal(err)
+	m, repo := openMigrator(cmd)
+	defer repo.Close()
+
+	if err := m.Down(); err != nil {
+		log.Fatalf("migrate down error: %v \n", err)
+	} else {
+		log.Print("Migrate down done with success")
 	}
-	config.DbDir = dbdir
+}
 
-	database := database.NewDatabaseRepository(context.Background(), dbdir)
+func migrateStatus(cmd *cobra.Command, args []string) {
+	m, repo := openMigrator(cmd)
+	defer repo.Close()
 
-	dbDriver, err := sqlite3.WithInstance(database.Db, &sqlite3.Config{})
+	available, err := database.AvailableMigrations()
 	if err != nil {
-		log.Fatalf("instance error: %v \n", err)
+		log.Fatalf("migrate status error: %v \n", err)
 	}
 
-	fileSource, err := (&file.File{}).Open("file://migrations")
-	if err != nil {
-		log.Fatalf("opening file error: %v \n", err)
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		log.Fatalf("migrate status error: %v \n", err)
+	}
+
+	if err == migrate.ErrNilVersion {
+		fmt.Println("current: none")
+	} else if dirty {
+		fmt.Printf("current: %d (dirty, fix the database and use force)\n", version)
+	} else {
+		fmt.Printf("current: %d\n", version)
+	}
+	fmt.Printf("latest: %d\n", available[len(available)-1])
+
+	for _, migration := range available {
+		state := "applied"
+		if err == migrate.ErrNilVersion || migration > version {
+			state = "pending"
+		}
+		fmt.Printf("%03d %s\n", migration, state)
 	}
+}
 
-	m, err := migrate.NewWithInstance("file", fileSource, "myDB", dbDriver)
+func migrateGoto(cmd *cobra.Command, args []string) {
+	version, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		log.Fatalf("migrate error: %v \n", err)
+		log.Fatalf("invalid version: %v \n", err)
 	}
 
-	if err = m.Down(); err != nil {
-		log.Fatalf("migrate down error: %v \n", err)
+	m, repo := openMigrator(cmd)
+	defer repo.Close()
+
+	if err = m.Migrate(uint(version)); err != nil {
+		if err == migrate.ErrNoChange {
+			log.Print("Already at this version")
+		} else {
+			log.Fatalf("migrate goto error: %v \n", err)
+		}
 	} else {
-		log.Print("Migrate down done with success")
+		log.Print("Migrate goto done with success", "version", version)
+	}
+}
+
+func migrateForce(cmd *cobra.Command, args []string) {
+	version, err := strconv.Atoi(args[0])
+	if err != nil {
+		log.Fatalf("invalid version: %v \n", err)
+	}
+
+	m, repo := openMigrator(cmd)
+	defer repo.Close()
+
+	if err = m.Force(version); err != nil {
+		log.Fatalf("migrate force error: %v \n", err)
 	}
+	log.Print("Migrate force done with success", "version", version)
 }
 
 func main() {
@@ -373,11 +597,19 @@ func main() {
 	rootCmd.PersistentFlags().Bool("version", false, "Print API version.")
 	rootCmd.PersistentFlags().Bool("skip-images", false, "Do not load (re)load images from the web.")
 	rootCmd.Flags().Bool("skip-almanax", false, "Do not initialize the Almanax.")
+	rootCmd.Flags().Bool("migrate", false, "Apply pending database migrations before starting.")
 	rootCmd.PersistentFlags().String("persistent-dir", ".", "Directory for persistent data like databases.")
 
 	migrateCmd.AddCommand(migrateDownCmd)
 	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateGotoCmd)
+	migrateCmd.AddCommand(migrateForceCmd)
 	rootCmd.AddCommand(migrateCmd)
+	almanaxIngestCmd.Flags().Bool("dry-run", false, "Only report which days would change.")
+	almanaxCmd.AddCommand(almanaxBackfillCmd)
+	almanaxCmd.AddCommand(almanaxIngestCmd)
+	rootCmd.AddCommand(almanaxCmd)
 
 	err := rootCmd.Execute()
 	if err != nil && err.Error() != "" {
@@ -407,6 +639,11 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 	}
 	config.SkipAlmanax = skipAlmanax
 
+	migrateOnStart, err := ccmd.Flags().GetBool("migrate")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	if printVersion {
 		fmt.Println(DoduapiVersionHelp)
 		return
@@ -458,12 +695,32 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 		log.Fatal(err)
 	}
 
+	almanaxRepo, err := database.OpenRepository(context.Background(), config.DbDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer almanaxRepo.Close()
+
+	if migrateOnStart || config.MigrateOnStart {
+		changed, err := almanaxRepo.MigrateUp()
+		if err != nil {
+			log.Fatal("could not migrate the database", "err", err)
+		}
+		if changed {
+			log.Info("applied pending database migrations")
+		}
+	}
+
+	if err = almanaxRepo.CheckSchema(); err != nil {
+		log.Fatal(err)
+	}
+
 	if !skipAlmanax {
 		if isChannelClosed(feedbackChan) {
 			os.Exit(1)
 		}
 		feedbackChan <- "Almanax"
-		err = almanax.GatherAlmanaxData(true, headless)
+		err = almanax.GatherAlmanaxData(almanaxRepo, config.DofusVersion, true, headless)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -473,12 +730,17 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	feedbackChan <- "Database"
+	if _, err = LoadSearchDictionaries(); err != nil {
+		log.Warn("could not load all search dictionaries", "err", err)
+	}
 	database.Db, database.Indexes = IndexApiData(&database.Version)
-	database.Version.Search = !database.Version.Search
+	if !database.SearchDegraded.Load() {
+		database.Version.Search = !database.Version.Search
+	}
 	database.Version.MemDb = !database.Version.MemDb
 
 	updateDb := make(chan *memdb.MemDB)
-	updateSearchIndex := make(chan map[string]database.SearchIndexes)
+	UpdateSearchIndex = make(chan map[string]database.SearchIndexes)
 	UpdateChan = make(chan utils.GameVersion)
 
 	if isChannelClosed(feedbackChan) {
@@ -488,7 +750,7 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 
 	httpDataServer = &http.Server{
 		Addr:    fmt.Sprintf(":%s", config.ApiPort),
-		Handler: Router(),
+		Handler: Router(almanaxRepo),
 	}
 
 	apiPort, _ := strconv.Atoi(config.ApiPort)
@@ -512,7 +774,16 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 		}
 	}()
 
-	go AutoUpdate(&database.Version, UpdateChan, updateDb, updateSearchIndex)
+	go AutoUpdate(&database.Version, almanaxRepo, UpdateChan, updateDb, UpdateSearchIndex)
+	if config.MeiliReconnectInterval > 0 {
+		go WatchSearchEngine(&database.Version, almanaxRepo, UpdateSearchIndex, config.MeiliReconnectInterval)
+	}
+	if config.SearchDictionaryReload > 0 {
+		go WatchSearchDictionaries(config.SearchDictionaryReload)
+	}
+	if !skipAlmanax && config.WebhookInterval > 0 {
+		go almanax.RunWebhookScheduler(almanaxRepo, config.WebhookInterval)
+	}
 
 	if !isChannelClosed(feedbackChan) {
 		close(feedbackChan)
@@ -526,9 +797,21 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 		releaseLog = "main"
 	}
 
-	config.CurrentVersion.Version = config.DofusVersion
-	config.CurrentVersion.UpdateStamp = time.Now()
-	config.CurrentVersion.Release = releaseLog
+	config.SetCurrentVersion(utils.GameVersion{
+		Version:     config.DofusVersion,
+		UpdateStamp: time.Now(),
+		Release:     releaseLog,
+	})
+
+	if config.ReleasePollInterval > 0 {
+		poller := &ReleasePoller{
+			Source:   NewGithubReleaseSource(releaseLog),
+			Interval: config.ReleasePollInterval,
+			Current:  func() string { return config.CurrentVersion().Version },
+			Trigger:  triggerUpdate,
+		}
+		go poller.Run(context.Background())
+	}
 
 	if config.PrometheusEnabled {
 		log.Print("Listening...", "port", apiPort, "metrics", apiPort+1, "release", releaseLog)
@@ -540,7 +823,7 @@ func rootCommand(ccmd *cobra.Command, args []string) {
 		for {
 			select {
 			case database.Db = <-updateDb: // override main memory with updated data
-			case database.Indexes = <-updateSearchIndex:
+			case database.Indexes = <-UpdateSearchIndex:
 			}
 		}
 	}()
@@ -19,7 +19,7 @@ func ListSearchAllTypes(w http.ResponseWriter, r *http.Request) {
 
 func GetGameVersion(w http.ResponseWriter, r *http.Request) {
 	utils.WriteCacheHeader(&w)
-	if err := json.NewEncoder(w).Encode(config.CurrentVersion); err != nil {
+	if err := json.NewEncoder(w).Encode(config.CurrentVersion()); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// metricsCollector records every request by its route pattern, so /items/equipment/{ankamaId} is one series and not
+// one per item. Requests that match no route share the "unmatched" route and unknown languages share "other".
+func metricsCollector(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route, lang := "", ""
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil {
+				route = routeCtx.RoutePattern()
+				lang = routeCtx.URLParam("lang")
+			}
+			if lang != "" && !slices.Contains(config.Languages, lang) {
+				lang = "other"
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+
+			labels := []string{route, r.Method, strconv.Itoa(status), lang}
+			utils.HttpRequests.WithLabelValues(labels...).Inc()
+			utils.HttpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			utils.HttpResponseSize.WithLabelValues(labels...).Observe(float64(ww.BytesWritten()))
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCollectorUsesRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(metricsCollector)
+	r.Get("/{lang}/items/equipment/{ankamaId}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+	r.Post("/update/{token}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	requests := func(labels ...string) float64 {
+		return testutil.ToFloat64(utils.HttpRequests.WithLabelValues(labels...))
+	}
+	item := []string{"/{lang}/items/equipment/{ankamaId}", http.MethodGet, "200", "en"}
+	otherLang := []string{"/{lang}/items/equipment/{ankamaId}", http.MethodGet, "200", "other"}
+	unmatched := []string{"unmatched", http.MethodGet, "404", ""}
+	hook := []string{"/update/{token}", http.MethodPost, "404", ""}
+	itemBefore, otherBefore, unmatchedBefore, hookBefore := requests(item...), requests(otherLang...), requests(unmatched...), requests(hook...)
+
+	for _, path := range []string{"/en/items/equipment/1", "/en/items/equipment/2", "/xx/items/equipment/1", "/zz/items/equipment/1", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/update/s3cret", nil))
+
+	if count := requests(item...) - itemBefore; count != 2 {
+		t.Errorf("Expected both items to count for one route, got %v", count)
+	}
+	if count := requests(otherLang...) - otherBefore; count != 2 {
+		t.Errorf("Expected unknown languages to count as other, got %v", count)
+	}
+	if count := requests(unmatched...) - unmatchedBefore; count != 1 {
+		t.Errorf("Expected the unknown path to count as unmatched, got %v", count)
+	}
+	if count := requests(hook...) - hookBefore; count != 1 {
+		t.Errorf("Expected the update hook to count without its token, got %v", count)
+	}
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if strings.Contains(label.GetValue(), "s3cret") {
+					t.Errorf("Expected the hook token to stay out of %s, got label %s", family.GetName(), label.GetValue())
+				}
+			}
+		}
+	}
+}
@@ -2,16 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
 	e "github.com/dofusdude/doduapi/errmsg"
 	"github.com/go-chi/chi/v5"
 )
 
+// almanaxRepositoryInjector hands the long-lived almanax database to the handlers.
+func almanaxRepositoryInjector(repo *database.Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "almanaxRepo", repo)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func disablePaginate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.WithValue(r.Context(), "pagination", "1,-1")
@@ -54,11 +67,23 @@ func paginate(next http.Handler) http.Handler {
 	})
 }
 
-func useCors(next http.Handler) http.Handler {
+// webhookTokenChecker only lets requests with "Authorization: Bearer <WEBHOOK_TOKEN>" through. Without a configured
+// token, webhook management is disabled.
+func webhookTokenChecker(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		ctx := context.WithValue(r.Context(), "cors", true)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		webhookToken := config.RuntimeConfig().WebhookToken
+		if webhookToken == "" {
+			e.WriteNotFoundResponse(w, "Webhooks are disabled.")
+			return
+		}
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(webhookToken)) != 1 {
+			e.WriteUnauthorizedResponse(w, "Invalid webhook token.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
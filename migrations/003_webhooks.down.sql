@@ -0,0 +1,5 @@
+drop index if exists idx_webhook_delivery_webhook_id;
+
+drop table if exists webhook_delivery;
+
+drop table if exists webhook;
@@ -0,0 +1,25 @@
+create table webhook (
+    id integer primary key autoincrement,
+    format text not null,
+    url text not null,
+    lang text not null,
+    bonus_types text not null default '',
+    last_delivered_date text,
+    created_at datetime default current_timestamp,
+    updated_at datetime default current_timestamp,
+    deleted_at datetime
+);
+
+create table webhook_delivery (
+    id integer primary key autoincrement,
+    webhook_id integer not null,
+    date text not null,
+    attempt integer not null,
+    status_code integer not null default 0,
+    error text not null default '',
+    success boolean not null default 0,
+    created_at datetime default current_timestamp,
+    foreign key (webhook_id) references webhook (id)
+);
+
+create index idx_webhook_delivery_webhook_id on webhook_delivery (webhook_id);
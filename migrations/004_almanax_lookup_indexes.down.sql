@@ -0,0 +1,7 @@
+drop index if exists idx_almanax_bonus_id;
+
+drop index if exists idx_almanax_tribute_id;
+
+drop index if exists idx_bonus_bonus_type_id;
+
+drop index if exists idx_tribute_item_ankama_id;
@@ -0,0 +1,7 @@
+create index idx_tribute_item_ankama_id on tribute (item_ankama_id);
+
+create index idx_bonus_bonus_type_id on bonus (bonus_type_id);
+
+create index idx_almanax_tribute_id on almanax (tribute_id);
+
+create index idx_almanax_bonus_id on almanax (bonus_id);
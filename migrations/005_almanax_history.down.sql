@@ -0,0 +1,12 @@
+drop index if exists idx_almanax_date_history;
+
+delete from almanax
+where
+    deleted_at is not null;
+
+drop index if exists idx_almanax_date;
+
+create unique index idx_almanax_date on almanax (date);
+
+alter table almanax
+drop column game_version;
@@ -0,0 +1,11 @@
+drop index if exists idx_almanax_date;
+
+alter table almanax
+add column game_version text not null default '';
+
+-- only the current assignment of a day is unique, replaced ones stay as history
+create unique index idx_almanax_date on almanax (date)
+where
+    deleted_at is null;
+
+create index idx_almanax_date_history on almanax (date, created_at);
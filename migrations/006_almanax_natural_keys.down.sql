@@ -0,0 +1,3 @@
+drop index if exists idx_tribute_natural_key;
+
+drop index if exists idx_bonus_natural_key;
@@ -0,0 +1,8 @@
+-- natural keys for upserting the ingested almanax data
+create unique index idx_bonus_natural_key on bonus (bonus_type_id, description_en)
+where
+    deleted_at is null;
+
+create unique index idx_tribute_natural_key on tribute (item_ankama_id, quantity)
+where
+    deleted_at is null;
@@ -0,0 +1,6 @@
+-- delivery ids of signed update hook calls, a known id is a replay
+create table update_delivery (
+    delivery_id text primary key,
+    version text not null default '',
+    received_at datetime default current_timestamp
+);
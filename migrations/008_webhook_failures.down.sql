@@ -0,0 +1,7 @@
+drop index if exists idx_webhook_delivery_date;
+
+alter table webhook
+drop column disabled_at;
+
+alter table webhook
+drop column failed_days;
@@ -0,0 +1,8 @@
+-- days in a row a webhook spent its attempts without a delivery, it is disabled after too many
+alter table webhook
+add column failed_days integer not null default 0;
+
+alter table webhook
+add column disabled_at datetime;
+
+create index idx_webhook_delivery_date on webhook_delivery (webhook_id, date);
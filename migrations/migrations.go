@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations of the almanax database, so the binary does not depend on the
+// working directory.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
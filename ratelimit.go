@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	e "github.com/dofusdude/doduapi/errmsg"
+)
+
+// rate limit classes, each with its own quota
+const (
+	rateLimitSearch   = "search"
+	rateLimitAll      = "all"
+	rateLimitSingle   = "single"
+	rateLimitDefault  = "default"
+	rateLimitAuthFail = "auth_fail" // requests with an unknown API key, per client IP
+)
+
+// parseRateLimitQuota reads quotas like "60/1m".
+func parseRateLimitQuota(raw string) (config.RateLimitQuota, error) {
+	requests, window, found := strings.Cut(raw, "/")
+	if !found {
+		return config.RateLimitQuota{}, fmt.Errorf("invalid rate limit %q, use <requests>/<window> like 60/1m", raw)
+	}
+
+	var quota config.RateLimitQuota
+	var err error
+	if quota.Requests, err = strconv.Atoi(requests); err != nil || quota.Requests <= 0 {
+		return config.RateLimitQuota{}, fmt.Errorf("invalid rate limit %q, requests must be a positive number", raw)
+	}
+	if quota.Window, err = time.ParseDuration(window); err != nil || quota.Window <= 0 {
+		return config.RateLimitQuota{}, fmt.Errorf("invalid rate limit %q, window must be a positive duration", raw)
+	}
+	return quota, nil
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, only when not allowed
+}
+
+// RateLimitStore keeps the token buckets. The in-memory store is enough for a single instance, instances behind a
+// load balancer need a shared store.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, quota config.RateLimitQuota, now time.Time) (RateLimitResult, error)
+}
+
+// rateLimitStore is the store the router uses, replace it for a shared one.
+var rateLimitStore RateLimitStore = NewMemoryRateLimitStore()
+
+type tokenBucket struct {
+	tokens    float64
+	capacity  float64
+	perSecond float64
+	last      time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, quota config.RateLimitQuota, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// buckets that are full again behave like new ones, so they can go
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, b := range s.buckets {
+			if b.refill(now); b.tokens >= b.capacity {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	bucket, found := s.buckets[key]
+	if !found {
+		bucket = &tokenBucket{tokens: float64(quota.Requests), last: now}
+		s.buckets[key] = bucket
+	}
+	// the quota of a key can change with a config reload
+	bucket.capacity = float64(quota.Requests)
+	bucket.perSecond = bucket.capacity / quota.Window.Seconds()
+	bucket.refill(now)
+
+	result := RateLimitResult{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) / bucket.perSecond * float64(time.Second))
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((bucket.capacity - bucket.tokens) / bucket.perSecond * float64(time.Second))
+	return result, nil
+}
+
+type apiKey struct {
+	Name   string
+	Secret []byte
+	Factor int // multiplies every quota
+}
+
+// apiKeys is swapped as a whole when the config is reloaded.
+var apiKeys atomic.Pointer[[]apiKey]
+
+// parseApiKeys reads API_KEYS, a comma separated list of name:factor:secret, for example "partner:10:s3cret".
+func parseApiKeys(raw string) ([]apiKey, error) {
+	keys := make([]apiKey, 0)
+	for i, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// never put the entry into the error, it could be the secret
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid api key at position %d, use name:factor:secret", i+1)
+		}
+
+		factor, err := strconv.Atoi(parts[1])
+		if err != nil || factor <= 0 {
+			return nil, fmt.Errorf("api key %s needs a positive quota factor", parts[0])
+		}
+		keys = append(keys, apiKey{Name: parts[0], Secret: []byte(parts[2]), Factor: factor})
+	}
+	return keys, nil
+}
+
+func findApiKey(keys []apiKey, secret string) *apiKey {
+	var match *apiKey
+	for i := range keys {
+		if subtle.ConstantTimeCompare([]byte(secret), keys[i].Secret) == 1 {
+			match = &keys[i]
+		}
+	}
+	return match
+}
+
+// rateLimitClass sorts a request path into the quota it counts against.
+func rateLimitClass(urlPath string) string {
+	last := path.Base(urlPath)
+	switch last {
+	case "search":
+		return rateLimitSearch
+	case "all":
+		return rateLimitAll
+	}
+	if _, err := strconv.Atoi(last); err == nil {
+		return rateLimitSingle
+	}
+	if _, err := time.Parse("2006-01-02", last); err == nil {
+		return rateLimitSingle
+	}
+	return rateLimitDefault
+}
+
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeRateLimitHeaders(w http.ResponseWriter, quota config.RateLimitQuota, result RateLimitResult) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(quota.Requests))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", quota.Requests, int(quota.Window.Seconds())))
+}
+
+// rejectApiKey answers a request with an unknown API key. Rejections count against the client IP, so keys can not be
+// guessed without a limit.
+func rejectApiKey(w http.ResponseWriter, r *http.Request, store RateLimitStore, quotas map[string]config.RateLimitQuota, client string) {
+	quota, found := quotas[rateLimitAuthFail]
+	if !found {
+		quota = quotas[rateLimitDefault]
+	}
+
+	result, err := store.Take(r.Context(), client+":"+rateLimitAuthFail, quota, time.Now())
+	if err != nil {
+		requestLog(r).Error("could not check rate limit", "err", err)
+	} else if !result.Allowed {
+		writeRateLimitHeaders(w, quota, result)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		e.WriteTooManyRequestsResponse(w, fmt.Sprintf("Too many invalid API keys, the quota of %d per %s is used up.", quota.Requests, quota.Window))
+		return
+	}
+
+	e.WriteUnauthorizedResponse(w, "Invalid API key.")
+}
+
+// rateLimiter counts requests per client IP or, with a valid X-API-Key header, per key. Every class of endpoint has
+// its own bucket, so heavy /all listings do not use up the quota for singles.
+func rateLimiter(store RateLimitStore, quotas map[string]config.RateLimitQuota) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class := rateLimitClass(r.URL.Path)
+			quota := quotas[class]
+			client := "ip:" + clientIp(r)
+
+			if secret := r.Header.Get("X-API-Key"); secret != "" {
+				var key *apiKey
+				if keys := apiKeys.Load(); keys != nil {
+					key = findApiKey(*keys, secret)
+				}
+				if key == nil {
+					rejectApiKey(w, r, store, quotas, client)
+					return
+				}
+				client = "key:" + key.Name
+				quota.Requests *= key.Factor
+			}
+
+			result, err := store.Take(r.Context(), client+":"+class, quota, time.Now())
+			if err != nil {
+				// a broken store must not take the API down with it
+				requestLog(r).Error("could not check rate limit", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writeRateLimitHeaders(w, quota, result)
+			if !result.Allowed {
+				retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				e.WriteTooManyRequestsResponse(w, fmt.Sprintf("The %s quota of %d requests per %s is used up.", class, quota.Requests, quota.Window))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+	e "github.com/dofusdude/doduapi/errmsg"
+)
+
+func TestMemoryRateLimitStoreRefills(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	quota := config.RateLimitQuota{Requests: 2, Window: 10 * time.Second}
+	now := time.Unix(1700000000, 0)
+
+	for i, expected := range []bool{true, true, false} {
+		result, _ := store.Take(context.Background(), "ip:1", quota, now)
+		if result.Allowed != expected {
+			t.Fatalf("Request %d: expected allowed %v", i+1, expected)
+		}
+	}
+
+	result, _ := store.Take(context.Background(), "ip:1", quota, now)
+	if result.RetryAfter != 5*time.Second || result.Reset != 10*time.Second {
+		t.Errorf("Expected retry after 5s and reset after 10s, got %s and %s", result.RetryAfter, result.Reset)
+	}
+
+	if result, _ = store.Take(context.Background(), "ip:1", quota, now.Add(5*time.Second)); !result.Allowed {
+		t.Error("Expected one token after 5s")
+	}
+	if result, _ = store.Take(context.Background(), "ip:2", quota, now); !result.Allowed {
+		t.Error("Expected another client to have its own bucket")
+	}
+}
+
+func TestRateLimitClass(t *testing.T) {
+	cases := map[string]string{
+		"/dofus3/v1/en/items/equipment/search": rateLimitSearch,
+		"/dofus3/v1/en/items/equipment/all":    rateLimitAll,
+		"/dofus3/v1/en/items/equipment/12345":  rateLimitSingle,
+		"/dofus3/v1/en/almanax/2024-05-01":     rateLimitSingle,
+		"/dofus3/v1/en/items/equipment":        rateLimitDefault,
+	}
+	for path, expected := range cases {
+		if class := rateLimitClass(path); class != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, class)
+		}
+	}
+}
+
+func TestRateLimiterResponses(t *testing.T) {
+	keys, err := parseApiKeys("partner:3:s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeys.Store(&keys)
+	t.Cleanup(func() { apiKeys.Store(nil) })
+
+	quotas := map[string]config.RateLimitQuota{rateLimitDefault: {Requests: 1, Window: time.Minute}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := rateLimiter(NewMemoryRateLimitStore(), quotas)(ok)
+
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/dofus3/v1/en/items/equipment", nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request(""); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected the first request to pass, got %d with remaining %s", rec.Code, rec.Header().Get("RateLimit-Remaining"))
+	}
+
+	rec := request("")
+	var apiErr e.ApiError
+	if err := json.NewDecoder(rec.Body).Decode(&apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusTooManyRequests || apiErr.Code != e.ERR_RATE_LIMITED || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected a rate limit error, got %d %+v retry after %s", rec.Code, apiErr, rec.Header().Get("Retry-After"))
+	}
+
+	if rec := request("s3cret"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "3" {
+		t.Errorf("Expected the api key to have its own, larger quota, got %d with limit %s", rec.Code, rec.Header().Get("RateLimit-Limit"))
+	}
+
+	if rec := request("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown api key to be rejected, got %d", rec.Code)
+	}
+	if rec := request("guess"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected guessing api keys to be rate limited, got %d retry after %s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := request("s3cret"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the valid api key to still pass, got %d", rec.Code)
+	}
+}
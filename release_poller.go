@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/utils"
+)
+
+const maxReleasePollBackoff = time.Hour
+
+// ReleaseSource tells the latest release of the game data.
+type ReleaseSource interface {
+	// LatestRelease returns the name of the latest release. It may return the release of the previous call again when
+	// nothing changed.
+	LatestRelease(ctx context.Context) (string, error)
+}
+
+// RateLimitedError is returned by a ReleaseSource that must not be asked again before RetryAfter.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// GithubReleaseSource reads the latest release of a GitHub repository. It sends the ETag of the last answer, so an
+// unchanged release costs a 304 instead of the whole body.
+type GithubReleaseSource struct {
+	Url    string
+	Token  string // optional, raises the rate limit
+	Client *http.Client
+
+	etag    string
+	release string
+}
+
+func NewGithubReleaseSource(release string) *GithubReleaseSource {
+	return &GithubReleaseSource{
+		Url:    fmt.Sprintf("https://api.github.com/repos/dofusdude/dofus3-%s/releases/latest", release),
+		Token:  config.GithubToken,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *GithubReleaseSource) LatestRelease(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified:
+		return s.release, nil
+	case res.StatusCode == http.StatusTooManyRequests || (res.StatusCode == http.StatusForbidden && res.Header.Get("X-RateLimit-Remaining") == "0"):
+		return "", &RateLimitedError{RetryAfter: githubRetryAfter(res.Header, time.Now())}
+	case res.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("unexpected status %s from %s", res.Status, s.Url)
+	}
+
+	var latest struct {
+		Name    string `json:"name"`
+		TagName string `json:"tag_name"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&latest); err != nil {
+		return "", err
+	}
+
+	release := utils.ReleaseVersion(latest.Name, latest.TagName)
+	if release == "" {
+		return "", fmt.Errorf("latest release of %s has no name", s.Url)
+	}
+
+	s.etag = res.Header.Get("ETag")
+	s.release = release
+	return release, nil
+}
+
+// githubRetryAfter reads Retry-After or, for the primary rate limit, the X-RateLimit-Reset timestamp.
+func githubRetryAfter(header http.Header, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if wait := time.Unix(reset, 0).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	return time.Minute
+}
+
+// ReleasePoller asks a ReleaseSource for new releases and triggers an update for each one that is not served yet. A
+// release whose update failed is triggered again on the next check.
+type ReleasePoller struct {
+	Source   ReleaseSource
+	Interval time.Duration
+	Current  func() string              // version that is served right now
+	Trigger  func(version string) error // starts the update, see triggerUpdate for errUpdateNotNeeded
+
+	failures int
+}
+
+// poll checks the source once and returns how long to wait before the next check, without jitter.
+func (p *ReleasePoller) poll(ctx context.Context) time.Duration {
+	release, err := p.Source.LatestRelease(ctx)
+	if err != nil {
+		p.failures++
+		wait := min(p.Interval<<min(p.failures, 6), max(p.Interval, maxReleasePollBackoff))
+
+		var rateLimited *RateLimitedError
+		if errors.As(err, &rateLimited) {
+			wait = max(wait, rateLimited.RetryAfter)
+		}
+		log.Warn("could not check for a new release", "err", err, "retry", wait)
+		return wait
+	}
+	p.failures = 0
+
+	if release == p.Current() {
+		return p.Interval
+	}
+
+	err = p.Trigger(release)
+	switch {
+	case errors.Is(err, errUpdateNotNeeded):
+		// the update is still on its way
+	case err != nil:
+		log.Error("could not start the update", "version", release, "err", err)
+	default:
+		log.Info("started the update to a new release", "version", release)
+	}
+	return p.Interval
+}
+
+// Run polls until ctx is done, starting one interval from now. Every wait is stretched by up to a tenth, so instances
+// started together do not ask at the same time and a rate limit is never cut short.
+func (p *ReleasePoller) Run(ctx context.Context) {
+	wait := p.Interval
+	for {
+		wait += time.Duration(rand.Float64() * float64(wait) / 10)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		wait = p.poll(ctx)
+	}
+}
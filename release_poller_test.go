@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeReleaseSource struct {
+	releases []string
+	errs     []error
+}
+
+func (s *fakeReleaseSource) LatestRelease(ctx context.Context) (string, error) {
+	release, err := s.releases[0], s.errs[0]
+	s.releases, s.errs = s.releases[1:], s.errs[1:]
+	return release, err
+}
+
+func TestReleasePollerTriggersNewReleasesOnce(t *testing.T) {
+	current := "3.0.1"
+	var guard updateGuard
+	var triggered []string
+	source := &fakeReleaseSource{
+		releases: []string{"3.0.1", "3.0.2", "3.0.2", "", "", "", "3.0.2"},
+		errs: []error{nil, nil, nil, errors.New("offline"), errors.New("offline"),
+			&RateLimitedError{RetryAfter: 3 * time.Hour}, nil},
+	}
+	poller := &ReleasePoller{
+		Source:   source,
+		Interval: time.Minute,
+		Current:  func() string { return current },
+		Trigger: func(version string) error {
+			if !guard.claim(version, current) {
+				return errUpdateNotNeeded
+			}
+			triggered = append(triggered, version)
+			return nil
+		},
+	}
+
+	expectedWaits := []time.Duration{time.Minute, time.Minute, time.Minute, 2 * time.Minute, 4 * time.Minute, 3 * time.Hour, time.Minute}
+	for i, expected := range expectedWaits {
+		if wait := poller.poll(context.Background()); wait != expected {
+			t.Errorf("Poll %d: expected to wait %s, got %s", i, expected, wait)
+		}
+	}
+
+	if len(triggered) != 1 || triggered[0] != "3.0.2" {
+		t.Errorf("Expected a single update to 3.0.2, got %v", triggered)
+	}
+}
+
+func TestReleasePollerRetriesFailedUpdates(t *testing.T) {
+	var triggered []string
+	poller := &ReleasePoller{
+		Source:   &fakeReleaseSource{releases: []string{"3.0.2", "3.0.2"}, errs: []error{nil, nil}},
+		Interval: time.Minute,
+		Current:  func() string { return "3.0.1" },
+		Trigger: func(version string) error {
+			triggered = append(triggered, version)
+			if len(triggered) == 1 {
+				return errors.New("could not download images")
+			}
+			return nil
+		},
+	}
+
+	poller.poll(context.Background())
+	poller.poll(context.Background())
+
+	if len(triggered) != 2 {
+		t.Errorf("Expected the failed update to be triggered again, got %v", triggered)
+	}
+}
+
+func TestGithubReleaseSourceUsesETag(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"name": "3.0.1", "tag_name": "v3.0.1"}`))
+		case 2:
+			if r.Header.Get("If-None-Match") != `"v1"` {
+				t.Errorf("Expected the ETag to be sent, got %q", r.Header.Get("If-None-Match"))
+			}
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	source := &GithubReleaseSource{Url: server.URL, Client: server.Client()}
+	for i := 0; i < 2; i++ {
+		release, err := source.LatestRelease(context.Background())
+		if err != nil || release != "3.0.1" {
+			t.Fatalf("Request %d: expected 3.0.1, got %q (%v)", i+1, release, err)
+		}
+	}
+
+	var rateLimited *RateLimitedError
+	if _, err := source.LatestRelease(context.Background()); !errors.As(err, &rateLimited) || rateLimited.RetryAfter != 2*time.Minute {
+		t.Errorf("Expected to be rate limited for 2m, got %v", err)
+	}
+}
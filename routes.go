@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/dofusdude/doduapi/almanax"
 	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -33,12 +34,24 @@ func FileServer(r chi.Router, path string, root http.FileSystem) {
 	})
 }
 
-func Router() chi.Router {
+func Router(almanaxRepo *database.Repository) chi.Router {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(requestIdentifier)
+	if config.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
+	r.Use(accessLogger)
+	if config.PrometheusEnabled {
+		r.Use(metricsCollector)
+	}
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(10 * time.Second))
 
+	rateLimit := func(next http.Handler) http.Handler { return next }
+	if config.RateLimitEnabled {
+		rateLimit = rateLimiter(rateLimitStore, config.RateLimitQuotas)
+	}
+
 	var gameRelease string
 	if config.IsBeta {
 		gameRelease = "dofus3beta"
@@ -46,40 +59,72 @@ func Router() chi.Router {
 		gameRelease = "dofus3"
 	}
 
-	r.With(useCors).Route(fmt.Sprintf("/%s/v%d", gameRelease, DoduapiMajor), func(r chi.Router) {
+	publicCors := cors(publicCorsPolicy())
+
+	r.Route(fmt.Sprintf("/%s/v%d", gameRelease, DoduapiMajor), func(r chi.Router) {
 
 		if config.PublishFileServer {
 			imagesDir := http.Dir(filepath.Join(config.DockerMountDataPath, "data", "img"))
-			FileServer(r, "/img", imagesDir)
+			FileServer(r.With(publicCors), "/img", imagesDir)
 		}
 
-		r.Route("/update", func(r chi.Router) {
-			r.Post(fmt.Sprintf("/%s", config.UpdateHookToken), UpdateHandler)
+		r.With(almanaxRepositoryInjector(almanaxRepo)).Route("/update", func(r chi.Router) {
+			r.Post("/{token}", UpdateHandler)
+		})
+
+		r.With(cors(adminCorsPolicy()), adminAuthenticator, almanaxRepositoryInjector(almanaxRepo)).Route("/admin", func(r chi.Router) {
+			r.With(requireAdminScope(adminScopeUpdate)).Post("/update", AdminTriggerUpdate)
+			r.With(requireAdminScope(adminScopeAlmanax)).Post("/almanax/gather", AdminGatherAlmanax)
+			r.With(requireAdminScope(adminScopeSearch)).Post("/search/rebuild", AdminRebuildSearchIndexes)
+			r.With(requireAdminScope(adminScopeConfig)).Post("/config/reload", AdminReloadConfig)
+			r.With(requireAdminScope(adminScopeRead)).Get("/generations", AdminGetGenerations)
+		})
+
+		r.With(cors(webhookCorsPolicy()), webhookTokenChecker, almanaxRepositoryInjector(almanaxRepo)).Route("/webhooks/almanax", func(r chi.Router) {
+			r.Get("/", almanax.ListWebhooks)
+			r.Post("/", almanax.CreateWebhook)
+			r.Get("/{webhookId}", almanax.GetWebhook)
+			r.Put("/{webhookId}", almanax.UpdateWebhook)
+			r.Delete("/{webhookId}", almanax.DeleteWebhook)
+			r.Get("/{webhookId}/deliveries", almanax.ListWebhookDeliveries)
 		})
 
-		r.Route("/meta", func(r chi.Router) {
+		r.With(publicCors, rateLimit).Route("/meta", func(r chi.Router) {
 			r.Get("/version", GetGameVersion)
 			r.Get("/elements", ListEffectConditionElements)
 			r.Get("/items/types", ListItemTypeIds)
 			r.Get("/search/types", ListSearchAllTypes)
+			r.Get("/search/synonyms", ListSearchDictionaries)
 
-			r.With(languageChecker).Route("/{lang}/almanax/bonuses", func(r chi.Router) {
+			r.With(languageChecker, almanaxRepositoryInjector(almanaxRepo)).Route("/{lang}/almanax/bonuses", func(r chi.Router) {
 				r.Get("/", almanax.ListBonuses)
 				r.Get("/search", almanax.SearchBonuses)
+				r.Get("/{bonusId}", almanax.GetBonus)
 			})
 		})
 
-		r.With(languageChecker).Route("/{lang}", func(r chi.Router) {
+		r.With(publicCors, languageChecker, rateLimit).Route("/{lang}", func(r chi.Router) {
 			r.Route("/search", func(r chi.Router) {
 				r.Get("/", SearchAllIndices)
 			})
 
-			r.Route("/almanax", func(r chi.Router) {
+			r.With(almanaxRepositoryInjector(almanaxRepo)).Route("/almanax", func(r chi.Router) {
 				r.Get("/", almanax.GetAlmanaxRange)
+				r.Get("/feed.rss", almanax.GetAlmanaxFeedRss)
+				r.Get("/feed.atom", almanax.GetAlmanaxFeedAtom)
+				r.Get("/calendar.ics", almanax.GetAlmanaxCalendar)
+				r.Get("/next", almanax.GetAlmanaxNext)
+				r.Get("/shopping-list", almanax.GetAlmanaxShoppingList)
+				r.Get("/shopping-list.csv", almanax.GetAlmanaxShoppingListCsv)
+				r.Get("/xp", almanax.GetAlmanaxXp)
+				r.Get("/today", almanax.GetAlmanaxToday)
+				r.Get("/tomorrow", almanax.GetAlmanaxTomorrow)
 				r.With(dateExtractor).Get("/{date}", almanax.GetAlmanaxSingle)
+				r.With(dateExtractor).Get("/{date}/history", almanax.GetAlmanaxHistory)
+				r.With(dateExtractor).Get("/{date}/xp", almanax.GetAlmanaxXp)
 			})
 
-			r.Route("/items", func(r chi.Router) {
+			r.With(almanaxRepositoryInjector(almanaxRepo)).Route("/items", func(r chi.Router) {
 				r.Route("/consumables", func(r chi.Router) {
 					r.With(paginate).Get("/", ListConsumables)
 					r.With(disablePaginate).Get("/all", ListAllConsumables)
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/almanax"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	"github.com/dofusdude/doduapi/utils"
+	mapping "github.com/dofusdude/dodumap"
+	"github.com/hashicorp/go-memdb"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	wordScoreWeight = 0.5
+	typoScoreWeight = 0.5
+)
+
+// searchIndexMutex serializes everything that writes search indexes (updates and reconnects).
+var searchIndexMutex sync.Mutex
+
+type searchHit struct {
+	Id          int
+	Score       float64
+	Fields      map[string]interface{} // raw document from the search engine, nil for in-memory results
+	FacetValues map[string]string      // facetable attributes of in-memory results
+}
+
+type searchResult struct {
+	Hits     []searchHit
+	Facets   map[string]map[string]int
+	Degraded bool
+}
+
+func meiliHits(searchResp *meilisearch.SearchResponse) []searchHit {
+	hits := make([]searchHit, 0, len(searchResp.Hits))
+	for _, hit := range searchResp.Hits {
+		indexed := hit.(map[string]interface{})
+		result := searchHit{
+			Id:     int(indexed["id"].(float64)),
+			Fields: indexed,
+		}
+
+		if details, ok := indexed["_rankingScoreDetails"].(map[string]interface{}); ok {
+			wordScore := details["words"].(map[string]interface{})["score"].(float64)
+			typoScore := details["typo"].(map[string]interface{})["score"].(float64)
+			result.Score = wordScore*wordScoreWeight + typoScore*typoScoreWeight
+		}
+
+		hits = append(hits, result)
+	}
+	return hits
+}
+
+// runSearch sends the request to the search engine. When the engine is known to be down or turns out to be unreachable,
+// fallback answers instead and the result is marked as degraded. Requested facets are then counted over all in-memory
+// matches.
+func runSearch(index meilisearch.IndexManager, query string, request *meilisearch.SearchRequest, fallback func() ([]searchHit, error)) (searchResult, error) {
+	if !database.SearchDegraded.Load() {
+		searchResp, err := index.Search(query, request)
+		if err == nil {
+			return searchResult{
+				Hits:   meiliHits(searchResp),
+				Facets: meiliFacets(searchResp),
+			}, nil
+		}
+
+		if !database.SearchEngineUnreachable(err) {
+			return searchResult{}, err
+		}
+
+		log.Warn("search engine unreachable, answering searches from memory", "err", err)
+		database.SearchDegraded.Store(true)
+	}
+
+	hits, err := fallback()
+	if err != nil {
+		return searchResult{Degraded: true}, err
+	}
+
+	result := searchResult{
+		Degraded: true,
+	}
+
+	if len(request.Facets) > 0 {
+		result.Facets = make(map[string]map[string]int)
+		for _, facet := range request.Facets {
+			result.Facets[facet] = make(map[string]int)
+		}
+		for _, hit := range hits {
+			for _, facet := range request.Facets {
+				if value, ok := hit.FacetValues[facet]; ok {
+					result.Facets[facet][value]++
+				}
+			}
+		}
+	}
+
+	if request.Limit > 0 && len(hits) > int(request.Limit) {
+		hits = hits[:request.Limit]
+	}
+	result.Hits = hits
+
+	return result, nil
+}
+
+// fallbackScore rates how well name matches query without a search engine. Matches at the start of the name rank
+// above matches at the start of any other word, which rank above all other substring matches.
+func fallbackScore(name string, query string) (float64, bool) {
+	name = strings.ToLower(name)
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return 0, false
+	}
+
+	idx := strings.Index(name, query)
+	switch {
+	case idx == -1:
+		return 0, false
+	case idx == 0:
+		return 1, true
+	case strings.ContainsAny(name[idx-1:idx], " -'("):
+		return 0.75, true
+	default:
+		return 0.5, true
+	}
+}
+
+// fallbackSearch scans a memdb table for entries whose name contains query. Results are ordered by fallbackScore and
+// then by name length, so the closest names come first.
+func fallbackSearch[T any](table string, query string, name func(T) string, id func(T) int, keep func(T) bool, facetValues func(T) map[string]string) ([]searchHit, error) {
+	txn := database.Db.Txn(false)
+	defer txn.Abort()
+
+	it, err := txn.Get(fmt.Sprintf("%s-%s", utils.CurrentRedBlueVersionStr(database.Version.MemDb), table), "id")
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]searchHit, 0)
+	nameLengths := make(map[int]int)
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		entry := obj.(T)
+		if keep != nil && !keep(entry) {
+			continue
+		}
+
+		entryName := name(entry)
+		score, ok := fallbackScore(entryName, query)
+		if !ok {
+			continue
+		}
+
+		entryId := id(entry)
+		nameLengths[entryId] = len(entryName)
+		hit := searchHit{
+			Id:    entryId,
+			Score: score,
+		}
+		if facetValues != nil {
+			hit.FacetValues = facetValues(entry)
+		}
+		hits = append(hits, hit)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return nameLengths[hits[i].Id] < nameLengths[hits[j].Id]
+	})
+
+	return hits, nil
+}
+
+func fallbackSearchItems(query string, lang string, keep func(*mapping.MappedMultilangItemUnity) bool) ([]searchHit, error) {
+	return fallbackSearch("all_items", query,
+		func(item *mapping.MappedMultilangItemUnity) string { return item.Name[lang] },
+		func(item *mapping.MappedMultilangItemUnity) int { return item.AnkamaId },
+		keep, itemFacetValues)
+}
+
+func fallbackSearchSets(query string, lang string, keep func(*mapping.MappedMultilangSetUnity) bool) ([]searchHit, error) {
+	return fallbackSearch("sets", query,
+		func(set *mapping.MappedMultilangSetUnity) string { return set.Name[lang] },
+		func(set *mapping.MappedMultilangSetUnity) int { return set.AnkamaId },
+		keep, nil)
+}
+
+func fallbackSearchMounts(query string, lang string, keep func(*mapping.MappedMultilangMount) bool) ([]searchHit, error) {
+	return fallbackSearch("mounts", query,
+		func(mount *mapping.MappedMultilangMount) string { return mount.Name[lang] },
+		func(mount *mapping.MappedMultilangMount) int { return mount.AnkamaId },
+		keep, nil)
+}
+
+func writeDegradedHeader(w http.ResponseWriter, degraded bool) {
+	if degraded {
+		w.Header().Set(utils.DegradedSearchHeader, "true")
+	}
+}
+
+func memDbTable[T any](txn *memdb.Txn, table string) ([]T, error) {
+	it, err := txn.Get(table, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []T
+	for obj := it.Next(); obj != nil; obj = it.Next() {
+		entries = append(entries, *obj.(*T))
+	}
+	return entries, nil
+}
+
+// RebuildSearchIndexes builds the next search index generation from the in-memory database, switches to it and
+// deletes the one that was active before, for example after the search engine was unreachable during startup or an
+// update. The new indexes are handed to the main loop through updateSearchIndex.
+func RebuildSearchIndexes(version *database.VersionT, almDb *database.Repository, updateSearchIndex chan map[string]database.SearchIndexes) error {
+	searchIndexMutex.Lock()
+	defer searchIndexMutex.Unlock()
+
+	txn := database.Db.Txn(false)
+	defer txn.Abort()
+
+	memDbVersion := utils.CurrentRedBlueVersionStr(version.MemDb)
+	items, err := memDbTable[mapping.MappedMultilangItemUnity](txn, fmt.Sprintf("%s-all_items", memDbVersion))
+	if err != nil {
+		return err
+	}
+
+	sets, err := memDbTable[mapping.MappedMultilangSetUnity](txn, fmt.Sprintf("%s-sets", memDbVersion))
+	if err != nil {
+		return err
+	}
+
+	mounts, err := memDbTable[mapping.MappedMultilangMount](txn, fmt.Sprintf("%s-mounts", memDbVersion))
+	if err != nil {
+		return err
+	}
+
+	recipes, err := memDbTable[mapping.MappedMultilangRecipe](txn, fmt.Sprintf("%s-recipes", memDbVersion))
+	if err != nil {
+		return err
+	}
+
+	client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
+	defer client.Close()
+
+	// leftovers of an earlier, interrupted build would otherwise stay in the new indexes
+	nextRedBlueVersion := utils.NextRedBlueVersionStr(version.Search)
+	if err = deleteSearchIndexes(nextRedBlueVersion); err != nil {
+		return err
+	}
+
+	indexes, err := GenerateSearchIndexes(&items, &sets, &recipes, &mounts, nextRedBlueVersion, client)
+	if err != nil {
+		return err
+	}
+	updateSearchIndex <- indexes
+
+	nowOldRedBlueVersion := utils.CurrentRedBlueVersionStr(version.Search)
+	version.Search = !version.Search // atomic version switch
+	if err = deleteSearchIndexes(nowOldRedBlueVersion); err != nil {
+		log.Error("Error while deleting old search indexes.", "err", err)
+	}
+
+	if !config.SkipAlmanax {
+		almanax.UpdateAlmanaxBonusIndex(true, almDb)
+	}
+
+	return nil
+}
+
+// WatchSearchEngine polls the search engine while the API runs in degraded search mode and rebuilds the indexes as
+// soon as it is reachable again.
+func WatchSearchEngine(version *database.VersionT, almDb *database.Repository, updateSearchIndex chan map[string]database.SearchIndexes, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !database.SearchDegraded.Load() {
+			continue
+		}
+
+		client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
+		healthy := client.IsHealthy()
+		client.Close()
+		if !healthy {
+			log.Debug("search engine still unreachable", "host", config.MeiliHost)
+			continue
+		}
+
+		log.Info("search engine reachable again, rebuilding indexes")
+		rebuildStart := time.Now()
+		if err := RebuildSearchIndexes(version, almDb, updateSearchIndex); err != nil {
+			log.Error("could not rebuild search indexes", "err", err)
+			continue
+		}
+
+		database.SearchDegraded.Store(false)
+		log.Info("search indexes rebuilt", "s", time.Since(rebuildStart).Seconds())
+	}
+}
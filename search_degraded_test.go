@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dofusdude/doduapi/database"
+	mapping "github.com/dofusdude/dodumap"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+func TestFallbackScoreOrdering(t *testing.T) {
+	prefix, ok := fallbackScore("Gelano", "gel")
+	if !ok || prefix != 1 {
+		t.Error("Expected prefix match with score 1, got ", prefix, ok)
+	}
+
+	word, ok := fallbackScore("Royal Gobball Set", "gob")
+	if !ok || word != 0.75 {
+		t.Error("Expected word match with score 0.75, got ", word, ok)
+	}
+
+	substring, ok := fallbackScore("Kralamoure", "lam")
+	if !ok || substring != 0.5 {
+		t.Error("Expected substring match with score 0.5, got ", substring, ok)
+	}
+
+	if _, ok := fallbackScore("Kralamoure", "gobball"); ok {
+		t.Error("Expected no match")
+	}
+
+	if _, ok := fallbackScore("Kralamoure", "  "); ok {
+		t.Error("Expected empty query to never match")
+	}
+}
+
+func TestLevelBucket(t *testing.T) {
+	cases := map[int]string{1: "1-20", 20: "1-20", 21: "21-40", 200: "181-200", 0: "1-20"}
+	for level, expected := range cases {
+		if bucket := levelBucket(level); bucket != expected {
+			t.Errorf("Expected level %d in bucket %s, got %s", level, expected, bucket)
+		}
+	}
+}
+
+func TestMeiliFacets(t *testing.T) {
+	searchResp := &meilisearch.SearchResponse{
+		FacetDistribution: map[string]interface{}{
+			"level_bucket":   map[string]interface{}{"1-20": float64(3), "181-200": float64(1)},
+			"has_parent_set": map[string]interface{}{"true": float64(2), "broken": "2"},
+		},
+	}
+
+	expected := map[string]map[string]int{
+		"level_bucket":   {"1-20": 3, "181-200": 1},
+		"has_parent_set": {"true": 2},
+	}
+	if facets := meiliFacets(searchResp); !reflect.DeepEqual(facets, expected) {
+		t.Errorf("Expected %v, got %v", expected, facets)
+	}
+
+	if facets := meiliFacets(&meilisearch.SearchResponse{}); facets != nil {
+		t.Error("Expected no facets without a distribution, got ", facets)
+	}
+}
+
+func TestRunSearchFallbackFacets(t *testing.T) {
+	database.SearchDegraded.Store(true)
+	t.Cleanup(func() { database.SearchDegraded.Store(false) })
+
+	item := func(level int, hasParentSet bool) searchHit {
+		unity := &mapping.MappedMultilangItemUnity{Level: level, HasParentSet: hasParentSet}
+		unity.Type.Name = map[string]string{"en": "Magic Weapon"}
+		return searchHit{FacetValues: itemFacetValues(unity)}
+	}
+
+	request := &meilisearch.SearchRequest{Limit: 2, Facets: []string{"level_bucket", "type.name_id"}}
+	result, err := runSearch(nil, "query", request, func() ([]searchHit, error) {
+		return []searchHit{item(5, true), item(15, false), item(190, false)}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Degraded || len(result.Hits) != 2 {
+		t.Errorf("Expected 2 degraded hits, got %d degraded %v", len(result.Hits), result.Degraded)
+	}
+
+	// facets count every match, not only the returned page
+	expected := map[string]map[string]int{
+		"level_bucket": {"1-20": 2, "181-200": 1},
+		"type.name_id": {"magic-weapon": 3},
+	}
+	if !reflect.DeepEqual(result.Facets, expected) {
+		t.Errorf("Expected %v, got %v", expected, result.Facets)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// SearchDictionary holds the synonyms and stop-words of one language. It is read from <SEARCH_DICTIONARY_DIR>/<lang>.json.
+type SearchDictionary struct {
+	Synonyms  map[string][]string `json:"synonyms"`
+	StopWords []string            `json:"stop_words"`
+}
+
+var (
+	searchDictionariesMutex sync.RWMutex
+	searchDictionaries      = make(map[string]SearchDictionary)
+	searchDictionaryModTime = make(map[string]time.Time) // of the files that were loaded
+	searchDictionaryApplied = make(map[string]time.Time) // of the files that made it into the search indexes
+)
+
+func searchDictionaryPath(lang string) string {
+	return filepath.Join(config.SearchDictionaryDir, fmt.Sprintf("%s.json", lang))
+}
+
+// loadSearchDictionary reads the dictionary of a language. A missing file is not an error and yields an empty dictionary.
+func loadSearchDictionary(lang string) (SearchDictionary, time.Time, error) {
+	dictionary := SearchDictionary{
+		Synonyms:  make(map[string][]string),
+		StopWords: make([]string, 0),
+	}
+
+	path := searchDictionaryPath(lang)
+	stat, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return dictionary, time.Time{}, nil
+	}
+	if err != nil {
+		return dictionary, time.Time{}, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return dictionary, time.Time{}, err
+	}
+
+	if err = json.Unmarshal(content, &dictionary); err != nil {
+		return dictionary, time.Time{}, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	if dictionary.Synonyms == nil {
+		dictionary.Synonyms = make(map[string][]string)
+	}
+	if dictionary.StopWords == nil {
+		dictionary.StopWords = make([]string, 0)
+	}
+
+	return dictionary, stat.ModTime(), nil
+}
+
+// LoadSearchDictionaries reads the dictionaries of all languages. It returns the languages whose files changed since
+// they were last applied to the search indexes, so a failed update is tried again with the next load. On a broken
+// file, the previously loaded dictionary of that language stays active.
+func LoadSearchDictionaries() ([]string, error) {
+	searchDictionariesMutex.Lock()
+	defer searchDictionariesMutex.Unlock()
+
+	var changed []string
+	var errs []error
+	for _, lang := range config.Languages {
+		dictionary, modTime, err := loadSearchDictionary(lang)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if applied, ok := searchDictionaryApplied[lang]; ok && modTime.Equal(applied) {
+			continue
+		}
+
+		searchDictionaries[lang] = dictionary
+		searchDictionaryModTime[lang] = modTime
+		changed = append(changed, lang)
+	}
+
+	return changed, errors.Join(errs...)
+}
+
+// markSearchDictionaryApplied records that the loaded dictionary of a language is in the search indexes.
+func markSearchDictionaryApplied(lang string) {
+	searchDictionariesMutex.Lock()
+	defer searchDictionariesMutex.Unlock()
+	searchDictionaryApplied[lang] = searchDictionaryModTime[lang]
+}
+
+func searchDictionary(lang string) SearchDictionary {
+	searchDictionariesMutex.RLock()
+	defer searchDictionariesMutex.RUnlock()
+	return searchDictionaries[lang]
+}
+
+// applySearchDictionary pushes the synonyms and stop-words of a language into its items, sets and mounts indexes.
+func applySearchDictionary(indexes database.SearchIndexes, lang string) ([]*meilisearch.TaskInfo, error) {
+	dictionary := searchDictionary(lang)
+
+	var tasks []*meilisearch.TaskInfo
+	for _, index := range []meilisearch.IndexManager{indexes.AllItems, indexes.Sets, indexes.Mounts} {
+		synonymsTask, err := index.UpdateSynonyms(&dictionary.Synonyms)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, synonymsTask)
+
+		stopWordsTask, err := index.UpdateStopWords(&dictionary.StopWords)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, stopWordsTask)
+	}
+
+	return tasks, nil
+}
+
+// WatchSearchDictionaries reloads the dictionary files when they change and updates the active search indexes, so
+// synonyms can be tuned without a full data update.
+func WatchSearchDictionaries(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		changed, err := LoadSearchDictionaries()
+		if err != nil {
+			log.Error("could not reload search dictionaries", "err", err)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		log.Info("search dictionaries changed", "languages", changed)
+		if database.SearchDegraded.Load() {
+			// the rebuild after reconnecting picks up the new dictionaries
+			for _, lang := range changed {
+				markSearchDictionaryApplied(lang)
+			}
+			continue
+		}
+
+		searchIndexMutex.Lock()
+		client := utils.NewMeiliClient(config.MeiliHost, config.MeiliKey)
+		for _, lang := range changed {
+			indexes, ok := database.Indexes[lang]
+			if !ok {
+				continue
+			}
+			tasks, err := applySearchDictionary(indexes, lang)
+			if err == nil {
+				err = waitForTasks(tasks, client, false)
+			}
+			if err != nil {
+				log.Error("could not update search dictionary, trying again with the next reload", "lang", lang, "err", err)
+				continue
+			}
+			markSearchDictionaryApplied(lang)
+		}
+		client.Close()
+		searchIndexMutex.Unlock()
+	}
+}
+
+func ListSearchDictionaries(w http.ResponseWriter, r *http.Request) {
+	searchDictionariesMutex.RLock()
+	defer searchDictionariesMutex.RUnlock()
+
+	utils.RequestsTotal.Inc()
+	utils.WriteCacheHeader(&w)
+	if err := json.NewEncoder(w).Encode(searchDictionaries); err != nil {
+		e.WriteServerErrorResponse(w, "Could not encode JSON: "+err.Error())
+		return
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/doduapi/config"
+)
+
+func testSearchDictionaries(t *testing.T) string {
+	t.Helper()
+
+	dir, languages := config.SearchDictionaryDir, config.Languages
+	config.SearchDictionaryDir = t.TempDir()
+	config.Languages = []string{"en", "fr"}
+	resetSearchDictionaries := func() {
+		searchDictionaries = make(map[string]SearchDictionary)
+		searchDictionaryModTime = make(map[string]time.Time)
+		searchDictionaryApplied = make(map[string]time.Time)
+	}
+	resetSearchDictionaries()
+	t.Cleanup(func() {
+		config.SearchDictionaryDir, config.Languages = dir, languages
+		resetSearchDictionaries()
+	})
+
+	return config.SearchDictionaryDir
+}
+
+func TestLoadSearchDictionariesRetriesUntilApplied(t *testing.T) {
+	dir := testSearchDictionaries(t)
+	if err := os.WriteFile(filepath.Join(dir, "fr.json"), []byte(`{"synonyms": {"pa": ["ap"]}, "stop_words": ["de"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := LoadSearchDictionaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"en", "fr"}) {
+		t.Errorf("Expected both languages on the first load, got %v", changed)
+	}
+	if dictionary := searchDictionary("fr"); !reflect.DeepEqual(dictionary.Synonyms["pa"], []string{"ap"}) || len(searchDictionary("en").StopWords) != 0 {
+		t.Errorf("Unexpected dictionaries %+v and %+v", dictionary, searchDictionary("en"))
+	}
+
+	// only en made it into the search indexes
+	markSearchDictionaryApplied("en")
+	if changed, _ = LoadSearchDictionaries(); !reflect.DeepEqual(changed, []string{"fr"}) {
+		t.Errorf("Expected fr to be tried again, got %v", changed)
+	}
+
+	markSearchDictionaryApplied("fr")
+	if changed, _ = LoadSearchDictionaries(); len(changed) != 0 {
+		t.Errorf("Expected nothing to change, got %v", changed)
+	}
+}
+
+func TestLoadSearchDictionariesKeepsBrokenFiles(t *testing.T) {
+	dir := testSearchDictionaries(t)
+	path := filepath.Join(dir, "en.json")
+	if err := os.WriteFile(path, []byte(`{"stop_words": ["the"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSearchDictionaries(); err != nil {
+		t.Fatal(err)
+	}
+	markSearchDictionaryApplied("en")
+	markSearchDictionaryApplied("fr")
+
+	if err := os.WriteFile(path, []byte(`{"stop_words": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := LoadSearchDictionaries()
+	if err == nil || len(changed) != 0 {
+		t.Errorf("Expected a parse error and no changes, got %v and %v", err, changed)
+	}
+	if stopWords := searchDictionary("en").StopWords; !reflect.DeepEqual(stopWords, []string{"the"}) {
+		t.Errorf("Expected the old dictionary to stay active, got %v", stopWords)
+	}
+}
+
+func TestListSearchDictionaries(t *testing.T) {
+	testSearchDictionaries(t)
+	if _, err := LoadSearchDictionaries(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	ListSearchDictionaries(rec, httptest.NewRequest(http.MethodGet, "/meta/search/synonyms", nil))
+
+	var listed map[string]SearchDictionary
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" || len(listed) != 2 {
+		t.Errorf("Expected both dictionaries as JSON, got %d %s %v", rec.Code, rec.Header().Get("Content-Type"), listed)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dofusdude/doduapi/utils"
+	mapping "github.com/dofusdude/dodumap"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const levelBucketSize = 20
+
+var itemSearchAllowedFacets = []string{"type.name_id", "super_type.name_id", "level_bucket", "has_parent_set"}
+
+// levelBucket groups levels into brackets of levelBucketSize, like "1-20" or "181-200".
+func levelBucket(level int) string {
+	if level < 1 {
+		level = 1
+	}
+	lower := ((level-1)/levelBucketSize)*levelBucketSize + 1
+	return fmt.Sprintf("%d-%d", lower, lower+levelBucketSize-1)
+}
+
+// itemFacetValues mirrors the facetable attributes of SearchIndexedItem for items matched in memory.
+func itemFacetValues(item *mapping.MappedMultilangItemUnity) map[string]string {
+	return map[string]string{
+		"type.name_id":       strings.ToLower(strings.ReplaceAll(item.Type.Name["en"], " ", "-")),
+		"super_type.name_id": utils.CategoryIdMapping(item.Type.CategoryId),
+		"level_bucket":       levelBucket(item.Level),
+		"has_parent_set":     strconv.FormatBool(item.HasParentSet),
+	}
+}
+
+func meiliFacets(searchResp *meilisearch.SearchResponse) map[string]map[string]int {
+	distribution, ok := searchResp.FacetDistribution.(map[string]interface{})
+	if !ok || len(distribution) == 0 {
+		return nil
+	}
+
+	facets := make(map[string]map[string]int, len(distribution))
+	for facet, rawCounts := range distribution {
+		counts := make(map[string]int)
+		if rawCountsMap, ok := rawCounts.(map[string]interface{}); ok {
+			for value, count := range rawCountsMap {
+				if countNum, ok := count.(float64); ok {
+					counts[value] = int(countNum)
+				}
+			}
+		}
+		facets[facet] = counts
+	}
+
+	return facets
+}
+
+// parseFacetsParam validates the comma separated facets query parameter.
+func parseFacetsParam(facetsParam string) ([]string, error) {
+	facets := parseFields(strings.ToLower(facetsParam))
+	if !validateFields(facets, itemSearchAllowedFacets) {
+		return nil, fmt.Errorf("allowed facets are %s", strings.Join(itemSearchAllowedFacets, ", "))
+	}
+	return facets.Keys(), nil
+}
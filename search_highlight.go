@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	highlightPreTag  = "<em>"
+	highlightPostTag = "</em>"
+)
+
+type ApiSearchHighlight struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	MatchedFields []string `json:"matched_fields"`
+}
+
+func parseHighlightParam(highlightStr string) (bool, error) {
+	if highlightStr == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(highlightStr)
+}
+
+// withHighlight asks the search engine for formatted snippets of the given attributes and the positions of all matches.
+// The first attribute is the one that gets cropped around the match.
+func withHighlight(request *meilisearch.SearchRequest, attributes []string) {
+	request.AttributesToHighlight = attributes
+	request.HighlightPreTag = highlightPreTag
+	request.HighlightPostTag = highlightPostTag
+	request.ShowMatchesPosition = true
+	if len(attributes) > 1 {
+		request.AttributesToCrop = attributes[1:]
+		request.CropLength = 20
+	}
+}
+
+// renderHighlight builds the highlight of a hit. Hits from the search engine carry their own formatted fields, hits
+// from the in-memory fallback only ever match on the name, so the marker is placed there.
+func renderHighlight(hit searchHit, name string, query string) *ApiSearchHighlight {
+	if hit.Fields == nil {
+		return &ApiSearchHighlight{
+			Name:          highlightSubstring(name, query),
+			MatchedFields: []string{"name"},
+		}
+	}
+
+	highlight := &ApiSearchHighlight{
+		Name:          name,
+		MatchedFields: make([]string, 0),
+	}
+
+	if formatted, ok := hit.Fields["_formatted"].(map[string]interface{}); ok {
+		if formattedName, ok := formatted["name"].(string); ok {
+			highlight.Name = formattedName
+		}
+		if formattedDescription, ok := formatted["description"].(string); ok {
+			highlight.Description = formattedDescription
+		}
+	}
+
+	if matches, ok := hit.Fields["_matchesPosition"].(map[string]interface{}); ok {
+		for field := range matches {
+			highlight.MatchedFields = append(highlight.MatchedFields, field)
+		}
+		sort.Strings(highlight.MatchedFields)
+	}
+
+	return highlight
+}
+
+// highlightSubstring marks the first case-insensitive match of query in name. Matching is done rune by rune on name
+// itself, since lowercasing can change the byte length of a string and the offsets would not fit name anymore.
+func highlightSubstring(name string, query string) string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return name
+	}
+
+	for start := range name {
+		if end, ok := foldedPrefixEnd(name[start:], query); ok {
+			end += start
+			return name[:start] + highlightPreTag + name[start:end] + highlightPostTag + name[end:]
+		}
+	}
+	return name
+}
+
+// foldedPrefixEnd reports whether s starts with prefix under simple case folding and how many bytes of s that takes.
+func foldedPrefixEnd(s string, prefix string) (int, bool) {
+	end := 0
+	for _, want := range prefix {
+		if end >= len(s) {
+			return 0, false
+		}
+		got, size := utf8.DecodeRuneInString(s[end:])
+		if !equalFoldRune(got, want) {
+			return 0, false
+		}
+		end += size
+	}
+	return end, true
+}
+
+func equalFoldRune(a rune, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
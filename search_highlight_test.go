@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestHighlightSubstring(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Gelano", "gel", "<em>Gel</em>ano"},
+		{"Royal Gobball Set", " gobball ", "Royal <em>Gobball</em> Set"},
+		{"Kralamoure", "xyz", "Kralamoure"},
+		{"Kralamoure", "  ", "Kralamoure"},
+		{"Épée de Boisaille", "épée", "<em>Épée</em> de Boisaille"},
+		{"Ka", "\u212a", "<em>K</em>a"},               // the Kelvin sign is longer than K but folds to it
+		{"\u212aelvin", "ke", "<em>\u212ae</em>lvin"}, // and the other way round
+		{"\u212aa", "kab", "\u212aa"},
+		{"ſtar", "ST", "<em>ſt</em>ar"},
+	}
+
+	for _, test := range tests {
+		if highlighted := highlightSubstring(test.name, test.query); highlighted != test.expected {
+			t.Errorf("%q with %q: expected %q, got %q", test.name, test.query, test.expected, highlighted)
+		}
+	}
+}
@@ -53,6 +53,13 @@ type ApiAllSearchResult struct {
 	Id         int                    `json:"ankama_id"`
 	Type       ApiAllSearchResultType `json:"type"`
 	ItemFields *ApiAllSearchItem      `json:"item_fields,omitempty"`
+	Highlight  *ApiSearchHighlight    `json:"highlight,omitempty"`
+}
+
+// APIFacetedAllSearch wraps the global search results when facets were requested. Facets are counted over items only.
+type APIFacetedAllSearch struct {
+	Results []ApiAllSearchResult      `json:"results"`
+	Facets  map[string]map[string]int `json:"facets"`
 }
 
 type ApiAllSearchResultScore struct {
@@ -118,6 +125,7 @@ type APIResource struct {
 	Effects     []ApiEffect       `json:"effects,omitempty"`
 	Conditions  *ApiConditionNode `json:"conditions,omitempty"`
 	Recipe      []APIRecipe       `json:"recipe,omitempty"`
+	Almanax     []APIItemAlmanax  `json:"almanax,omitempty"`
 }
 
 func RenderResource(item *mapping.MappedMultilangItemUnity, lang string) APIResource {
@@ -160,6 +168,7 @@ type APIEquipment struct {
 	Conditions  *ApiConditionNode  `json:"conditions,omitempty"`
 	Recipe      []APIRecipe        `json:"recipe,omitempty"`
 	ParentSet   *APISetReverseLink `json:"parent_set,omitempty"`
+	Almanax     []APIItemAlmanax   `json:"almanax,omitempty"`
 }
 
 func RenderEquipment(item *mapping.MappedMultilangItemUnity, lang string) APIEquipment {
@@ -226,6 +235,7 @@ type APIWeapon struct {
 	Range                  APIRange           `json:"range"`
 	Recipe                 []APIRecipe        `json:"recipe,omitempty"`
 	ParentSet              *APISetReverseLink `json:"parent_set,omitempty"`
+	Almanax                []APIItemAlmanax   `json:"almanax,omitempty"`
 }
 
 func RenderWeapon(item *mapping.MappedMultilangItemUnity, lang string) APIWeapon {
@@ -367,6 +377,7 @@ type APIListItem struct {
 	Recipe      []APIRecipe       `json:"recipe,omitempty"`
 	Conditions  *ApiConditionNode `json:"conditions,omitempty"`
 	Effects     []ApiEffect       `json:"effects,omitempty"`
+	Almanax     []APIItemAlmanax  `json:"almanax,omitempty"`
 
 	// extra equipment
 	IsWeapon  *bool              `json:"is_weapon,omitempty"`
@@ -379,6 +390,9 @@ type APIListItem struct {
 	MaxCastPerTurn         *int      `json:"max_cast_per_turn,omitempty"`
 	ApCost                 *int      `json:"ap_cost,omitempty"`
 	Range                  *APIRange `json:"range,omitempty"`
+
+	// search only
+	Highlight *ApiSearchHighlight `json:"highlight,omitempty"`
 }
 
 func RenderItemListEntry(item *mapping.MappedMultilangItemUnity, lang string) APIListItem {
@@ -406,6 +420,9 @@ type APIListTypedItem struct {
 	ItemSubtype APIListItemType `json:"item_subtype"`
 	Level       int             `json:"level"`
 	ImageUrls   ApiImageUrls    `json:"image_urls,omitempty"`
+
+	// search only
+	Highlight *ApiSearchHighlight `json:"highlight,omitempty"`
 }
 
 func RenderTypedItemListEntry(item *mapping.MappedMultilangItemUnity, lang string) APIListTypedItem {
@@ -437,6 +454,27 @@ func RenderMountListEntry(mount *mapping.MappedMultilangMount, lang string) APIM
 	}
 }
 
+// APIItemAlmanax is an upcoming almanax day where the item is the tribute.
+type APIItemAlmanax struct {
+	Date     string `json:"date"`
+	Quantity int    `json:"quantity"`
+}
+
+func RenderItemAlmanax(tributes []database.TributeDate) []APIItemAlmanax {
+	if len(tributes) == 0 {
+		return nil
+	}
+
+	res := make([]APIItemAlmanax, 0, len(tributes))
+	for _, tribute := range tributes {
+		res = append(res, APIItemAlmanax{
+			Date:     tribute.Date,
+			Quantity: tribute.Quantity,
+		})
+	}
+	return res
+}
+
 type APIRecipe struct {
 	AnkamaId int    `json:"item_ankama_id"`
 	ItemType string `json:"item_subtype"`
@@ -474,6 +512,16 @@ type APIPageItem struct {
 	Items []APIListItem         `json:"items"`
 }
 
+type APIFacetedItemSearch struct {
+	Items  []APIListItem             `json:"items"`
+	Facets map[string]map[string]int `json:"facets"`
+}
+
+type APIFacetedTypedItemSearch struct {
+	Items  []APIListTypedItem        `json:"items"`
+	Facets map[string]map[string]int `json:"facets"`
+}
+
 type APIPageMount struct {
 	Links utils.PaginationLinks `json:"_links,omitempty"`
 	Items []APIMount            `json:"mounts"`
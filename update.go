@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	e "github.com/dofusdude/doduapi/errmsg"
+	"github.com/dofusdude/doduapi/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxUpdatePayloadSize = 1 << 20
+
+// UpdateMessage is the body of an update hook call. Besides {"version": "..."}, the body can be a GitHub release
+// event, then the version is the tag of the published release.
+type UpdateMessage struct {
+	Version string `json:"version"`
+
+	Action  string `json:"action"`
+	Release *struct {
+		TagName string `json:"tag_name"`
+		Name    string `json:"name"`
+		Draft   bool   `json:"draft"`
+	} `json:"release"`
+}
+
+// updateVersion returns the game version to update to. It is empty for release events that do not publish a release.
+func (m *UpdateMessage) updateVersion() (string, error) {
+	if m.Release == nil {
+		if m.Version == "" {
+			return "", fmt.Errorf("version is required")
+		}
+		return m.Version, nil
+	}
+
+	if m.Action != "published" || m.Release.Draft {
+		return "", nil
+	}
+
+	if version := utils.ReleaseVersion(m.Release.Name, m.Release.TagName); version != "" {
+		return version, nil
+	}
+	return "", fmt.Errorf("release has no tag")
+}
+
+// validHubSignature checks a GitHub style X-Hub-Signature-256 header, the HMAC-SHA256 of the body as "sha256=<hex>".
+func validHubSignature(secret string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+type UpdateHookResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+}
+
+func writeUpdateHookResponse(w http.ResponseWriter, status int, res UpdateHookResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Error("could not encode update hook response", "err", err)
+	}
+}
+
+// UpdateHandler starts an update from a hook call to /update/<UPDATE_HOOK_TOKEN>. With UPDATE_HOOK_SECRET set, the body
+// must be signed like a GitHub webhook and every X-GitHub-Delivery id is only accepted once. Without a token, the hook
+// is disabled. It is deprecated in favour of /admin/update.
+func UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if config.UpdateHookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.UpdateHookToken)) != 1 {
+		e.WriteNotFoundResponse(w, "Unknown update hook.")
+		return
+	}
+
+	successor := strings.TrimSuffix(chi.RouteContext(r.Context()).RoutePattern(), "/update/{token}") + "/admin/update"
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+	requestLog(r).Warn("the update hook is deprecated, use the admin API", "successor", successor)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUpdatePayloadSize))
+	if err != nil {
+		e.WriteInvalidJsonResponse(w, "Could not read body: "+err.Error())
+		return
+	}
+
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	if secret := config.RuntimeConfig().UpdateHookSecret; secret != "" {
+		if !validHubSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			e.WriteUnauthorizedResponse(w, "Invalid X-Hub-Signature-256.")
+			return
+		}
+
+		if deliveryID == "" {
+			e.WriteUnauthorizedResponse(w, "Signed update hooks need a X-GitHub-Delivery id.")
+			return
+		}
+	}
+
+	if r.Header.Get("X-GitHub-Event") == "ping" {
+		writeUpdateHookResponse(w, http.StatusOK, UpdateHookResponse{Status: "pong"})
+		return
+	}
+
+	var updateMessage UpdateMessage
+	if err = json.Unmarshal(body, &updateMessage); err != nil {
+		e.WriteInvalidJsonResponse(w, err.Error())
+		return
+	}
+
+	version, err := updateMessage.updateVersion()
+	if err != nil {
+		e.WriteInvalidJsonResponse(w, err.Error())
+		return
+	}
+
+	if version == "" {
+		writeUpdateHookResponse(w, http.StatusAccepted, UpdateHookResponse{Status: "ignored"})
+		return
+	}
+
+	if deliveryID != "" {
+		almDb := r.Context().Value("almanaxRepo").(*database.Repository)
+		fresh, err := almDb.RecordUpdateDelivery(deliveryID, version)
+		if err != nil {
+			e.WriteServerErrorResponse(w, "Could not record delivery: "+err.Error())
+			return
+		}
+
+		if !fresh {
+			e.WriteConflictResponse(w, fmt.Sprintf("Delivery %s was already processed.", deliveryID))
+			return
+		}
+
+		err = updateTrigger(version)
+		if err != nil && !errors.Is(err, errUpdateNotNeeded) {
+			// the update never started, so GitHub may deliver it again
+			if forgetErr := almDb.ForgetUpdateDelivery(deliveryID); forgetErr != nil {
+				requestLog(r).Error("could not forget update delivery", "delivery", deliveryID, "err", forgetErr)
+			}
+		}
+		writeUpdateTriggered(w, version, err)
+		return
+	}
+
+	writeUpdateTriggered(w, version, updateTrigger(version))
+}
+
+func writeUpdateTriggered(w http.ResponseWriter, version string, err error) {
+	if errors.Is(err, errUpdateNotNeeded) {
+		writeUpdateHookResponse(w, http.StatusOK, UpdateHookResponse{Status: "unchanged", Version: version})
+		return
+	}
+	if err != nil {
+		e.WriteServerErrorResponse(w, err.Error())
+		return
+	}
+
+	writeUpdateHookResponse(w, http.StatusOK, UpdateHookResponse{Status: "updating", Version: version})
+}
+
+// errUpdateNotNeeded is returned by triggerUpdate for a version that is served already or on its way there.
+var errUpdateNotNeeded = errors.New("version is already served or being updated to")
+
+// updateGuard hands every version to the update loop only once while it is served or still on its way there, no
+// matter if the hook, the admin API or the release poller asks for it. A captured hook body sent again with another
+// delivery id does not start a second update.
+type updateGuard struct {
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// claim reports false if version is current or pending already, otherwise it is pending from now on.
+func (g *updateGuard) claim(version string, current string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if version == current || g.pending[version] {
+		return false
+	}
+	if g.pending == nil {
+		g.pending = make(map[string]bool)
+	}
+	g.pending[version] = true
+	return true
+}
+
+// release lets the version be claimed again, after its update failed or was applied.
+func (g *updateGuard) release(version string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.pending, version)
+}
+
+var pendingUpdates updateGuard
+
+// updateTrigger starts the update of the hook and the admin API.
+var updateTrigger = triggerUpdate
+
+// triggerUpdate downloads the images of a game version and hands it to the update loop. It returns errUpdateNotNeeded
+// if the version is served already or on its way there.
+func triggerUpdate(version string) error {
+	if !pendingUpdates.claim(version, config.CurrentVersion().Version) {
+		return errUpdateNotNeeded
+	}
+
+	var release string
+	if config.IsBeta {
+		release = "beta"
+	} else {
+		release = "main"
+	}
+
+	config.ReleaseUrl = fmt.Sprintf("https://github.com/dofusdude/dofus3-%s/releases/download/%s", release, version)
+
+	log.Info("Updating to version", version)
+	err := utils.DownloadImages(config.DockerMountDataPath, config.ReleaseUrl)
+	if err != nil {
+		pendingUpdates.release(version)
+		return fmt.Errorf("could not download images: %w", err)
+	}
+
+	newVersion := utils.GameVersion{
+		Version:     version,
+		Release:     release,
+		UpdateStamp: time.Now(),
+	}
+
+	UpdateChan <- newVersion
+	return nil
+}
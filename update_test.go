@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dofusdude/doduapi/config"
+	"github.com/dofusdude/doduapi/database"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestUpdateVersionFromPayload(t *testing.T) {
+	cases := map[string]string{
+		`{"version": "3.0.40.28"}`: "3.0.40.28",
+		`{"action": "published", "release": {"tag_name": "v3.1.2.4", "name": "3.1.2.4"}}`: "3.1.2.4",
+		`{"action": "published", "release": {"tag_name": "3.1.2.4"}}`:                     "3.1.2.4",
+		`{"action": "published", "release": {"name": "3.1.2.4"}}`:                         "3.1.2.4",
+		`{"action": "created", "release": {"tag_name": "3.1.2.4"}}`:                       "",
+		`{"action": "published", "release": {"tag_name": "3.1.2.4", "draft": true}}`:      "",
+	}
+	for payload, expected := range cases {
+		var message UpdateMessage
+		if err := json.Unmarshal([]byte(payload), &message); err != nil {
+			t.Fatal(err)
+		}
+		version, err := message.updateVersion()
+		if err != nil || version != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", payload, expected, version, err)
+		}
+	}
+
+	var empty UpdateMessage
+	if _, err := empty.updateVersion(); err == nil {
+		t.Error("Expected an error without version")
+	}
+}
+
+func TestValidHubSignature(t *testing.T) {
+	// example from the GitHub webhook documentation
+	secret := "It's a Secret to Everybody"
+	body := []byte("Hello, World!")
+	signature := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	if !validHubSignature(secret, body, signature) {
+		t.Error("Expected the signature to be valid")
+	}
+	if validHubSignature(secret, []byte("Hello, World?"), signature) {
+		t.Error("Expected a changed body to be rejected")
+	}
+	if validHubSignature("other", body, signature) {
+		t.Error("Expected another secret to be rejected")
+	}
+}
+
+func TestUpdateHookToken(t *testing.T) {
+	router := chi.NewRouter()
+	router.Route("/dofus3/v1/update", func(r chi.Router) {
+		r.Post("/{token}", UpdateHandler)
+	})
+
+	ping := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/dofus3/v1/update/"+token, strings.NewReader("{}"))
+		req.Header.Set("X-GitHub-Event", "ping")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	config.UpdateHookToken = ""
+	t.Cleanup(func() { config.UpdateHookToken = "" })
+	if rec := ping("anything"); rec.Code != http.StatusNotFound {
+		t.Error("Expected the hook to be disabled without a token, got ", rec.Code)
+	}
+
+	config.UpdateHookToken = "hook-secret"
+	if rec := ping("wrong"); rec.Code != http.StatusNotFound {
+		t.Error("Expected a wrong token to be unknown, got ", rec.Code)
+	}
+
+	rec := ping("hook-secret")
+	if rec.Code != http.StatusOK {
+		t.Fatal("Expected the ping to be answered, got ", rec.Code)
+	}
+	if rec.Header().Get("Deprecation") != "true" || rec.Header().Get("Link") != `</dofus3/v1/admin/update>; rel="successor-version"` {
+		t.Errorf("Expected the hook to point to its successor, got %q and %q", rec.Header().Get("Deprecation"), rec.Header().Get("Link"))
+	}
+}
+
+func TestUpdateGuard(t *testing.T) {
+	var guard updateGuard
+
+	if !guard.claim("3.0.2", "3.0.1") {
+		t.Error("Expected a new version to be claimed")
+	}
+	if guard.claim("3.0.2", "3.0.1") {
+		t.Error("Expected a pending version to be skipped")
+	}
+	if guard.claim("3.0.1", "3.0.1") {
+		t.Error("Expected the current version to be skipped")
+	}
+	if !guard.claim("3.0.3", "3.0.1") {
+		t.Error("Expected another version to be claimed while one is pending")
+	}
+
+	guard.release("3.0.2")
+	if !guard.claim("3.0.2", "3.0.1") {
+		t.Error("Expected a released version to be claimed again")
+	}
+}
+
+func TestUpdateHookDeliveries(t *testing.T) {
+	repo, err := database.OpenRepository(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	if _, err = repo.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	config.UpdateHookToken = "hook-secret"
+	var triggerErr error
+	var triggered []string
+	updateTrigger = func(version string) error {
+		triggered = append(triggered, version)
+		return triggerErr
+	}
+	t.Cleanup(func() {
+		config.UpdateHookToken = ""
+		updateTrigger = triggerUpdate
+	})
+
+	router := chi.NewRouter()
+	router.With(almanaxRepositoryInjector(repo)).Post("/update/{token}", UpdateHandler)
+
+	deliver := func(deliveryID string, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/update/hook-secret", strings.NewReader(`{"version": "`+version+`"}`))
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	triggerErr = errors.New("could not download images")
+	if rec := deliver("d1", "3.0.2"); rec.Code != http.StatusInternalServerError {
+		t.Error("Expected a failed update to be reported, got ", rec.Code)
+	}
+
+	triggerErr = nil
+	if rec := deliver("d1", "3.0.2"); rec.Code != http.StatusOK {
+		t.Error("Expected a failed delivery to be accepted again, got ", rec.Code)
+	}
+	if rec := deliver("d1", "3.0.2"); rec.Code != http.StatusConflict {
+		t.Error("Expected a processed delivery to be rejected, got ", rec.Code)
+	}
+
+	triggerErr = errUpdateNotNeeded
+	rec := deliver("d2", "3.0.2")
+	var res UpdateHookResponse
+	if err = json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || res.Status != "unchanged" {
+		t.Errorf("Expected a replayed body to change nothing, got %d %q", rec.Code, res.Status)
+	}
+	if rec := deliver("d2", "3.0.2"); rec.Code != http.StatusConflict {
+		t.Error("Expected a skipped delivery to stay processed, got ", rec.Code)
+	}
+
+	if len(triggered) != 3 {
+		t.Errorf("Expected 3 triggered updates, got %v", triggered)
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// meiliTransport records the latency of every search engine call by its operation, so searches, indexing, settings
+// and task polling are all measured without timing each call site.
+type meiliTransport struct {
+	next http.RoundTripper
+}
+
+func (t meiliTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timer := prometheus.NewTimer(MeiliRequestDuration.WithLabelValues(meiliOperation(req.Method, req.URL.Path)))
+	defer timer.ObserveDuration()
+	return t.next.RoundTrip(req)
+}
+
+var meiliHttpClient = &http.Client{Transport: meiliTransport{next: http.DefaultTransport}}
+
+// NewMeiliClient connects to the search engine with calls timed in MeiliRequestDuration.
+func NewMeiliClient(host string, key string) meilisearch.ServiceManager {
+	return meilisearch.New(host, meilisearch.WithAPIKey(key), meilisearch.WithCustomClient(meiliHttpClient))
+}
+
+// meiliOperation names a search engine call by its method and path, without the index uid or task id.
+func meiliOperation(method string, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch parts[0] {
+	case "health":
+		return "health"
+	case "tasks":
+		return "task"
+	case "indexes":
+	default:
+		return "other"
+	}
+
+	if len(parts) == 1 {
+		if method == http.MethodPost {
+			return "create_index"
+		}
+		return "list_indexes"
+	}
+
+	if len(parts) == 2 {
+		switch method {
+		case http.MethodDelete:
+			return "delete_index"
+		case http.MethodGet:
+			return "get_index"
+		}
+		return "update_index"
+	}
+
+	switch parts[2] {
+	case "search":
+		return "search"
+	case "settings":
+		return "settings"
+	case "documents":
+		if method == http.MethodDelete || (len(parts) > 3 && strings.HasPrefix(parts[3], "delete")) {
+			return "delete_documents"
+		}
+		if method == http.MethodGet {
+			return "get_documents"
+		}
+		return "add_documents"
+	}
+	return "other"
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMeiliOperation(t *testing.T) {
+	for _, test := range []struct {
+		method    string
+		path      string
+		operation string
+	}{
+		{http.MethodGet, "/health", "health"},
+		{http.MethodGet, "/tasks/12", "task"},
+		{http.MethodPost, "/indexes", "create_index"},
+		{http.MethodGet, "/indexes", "list_indexes"},
+		{http.MethodGet, "/indexes/all_items-en-red", "get_index"},
+		{http.MethodDelete, "/indexes/all_items-en-red", "delete_index"},
+		{http.MethodPatch, "/indexes/all_items-en-red", "update_index"},
+		{http.MethodPost, "/indexes/all_items-en-red/search", "search"},
+		{http.MethodPost, "/indexes/all_items-en-red/documents", "add_documents"},
+		{http.MethodPut, "/indexes/all_items-en-red/documents", "add_documents"},
+		{http.MethodGet, "/indexes/all_items-en-red/documents/1", "get_documents"},
+		{http.MethodDelete, "/indexes/all_items-en-red/documents", "delete_documents"},
+		{http.MethodPost, "/indexes/all_items-en-red/documents/delete-batch", "delete_documents"},
+		{http.MethodPatch, "/indexes/all_items-en-red/settings", "settings"},
+		{http.MethodPut, "/indexes/all_items-en-red/settings/synonyms", "settings"},
+		{http.MethodGet, "/version", "other"},
+	} {
+		if operation := meiliOperation(test.method, test.path); operation != test.operation {
+			t.Errorf("Expected %s %s to be %s, got %s", test.method, test.path, test.operation, operation)
+		}
+	}
+}
@@ -16,6 +16,11 @@ var (
 		Help: "The total number of searches on the global /search endpoint",
 	})
 
+	RequestsAlmanaxBonusSearch = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dofus_requestsAlmanaxBonusSearch",
+		Help: "The total number of searched almanax bonus requests",
+	})
+
 	RequestsItemsSearch = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "dofus_requestsAllItemsSearch",
 		Help: "The total number of searched items requests",
@@ -71,3 +76,52 @@ var (
 		Help: "The total number of almanax range requests",
 	})
 )
+
+// labeled metrics, filled by the metrics middleware and the update loop
+
+var (
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dofus_http_requests_total",
+		Help: "The total number of requests by route pattern, method, status and language",
+	}, []string{"route", "method", "status", "lang"})
+
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dofus_http_request_duration_seconds",
+		Help:    "The time it took to answer requests by route pattern, method, status and language",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status", "lang"})
+
+	HttpResponseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dofus_http_response_size_bytes",
+		Help:    "The size of response bodies by route pattern, method, status and language",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 9), // 256 B to 16 MiB
+	}, []string{"route", "method", "status", "lang"})
+
+	MemDbEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dofus_memdb_entries",
+		Help: "The number of items, sets and mounts in each red/blue generation of the in-memory database",
+	}, []string{"generation", "kind"})
+
+	UpdateDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dofus_update_duration_seconds",
+		Help: "The time the last update took",
+	})
+
+	LastSuccessfulUpdate = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dofus_last_successful_update_timestamp_seconds",
+		Help: "The unix time when the last update finished",
+	})
+
+	MeiliRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dofus_meilisearch_request_duration_seconds",
+		Help:    "The latency of search engine calls by operation",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+)
+
+// SetMemDbEntries records the size of a generation, all zero once it is cleared.
+func SetMemDbEntries(generation string, items int, sets int, mounts int) {
+	MemDbEntries.WithLabelValues(generation, "items").Set(float64(items))
+	MemDbEntries.WithLabelValues(generation, "sets").Set(float64(sets))
+	MemDbEntries.WithLabelValues(generation, "mounts").Set(float64(mounts))
+}
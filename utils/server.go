@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// DegradedSearchHeader is set on search responses that were answered from memory instead of the search engine.
+const DegradedSearchHeader = "X-Search-Degraded"
+
 func SetJsonHeader(w *http.ResponseWriter) {
 	(*w).Header().Set("Content-Type", "application/json")
 }
@@ -16,6 +19,15 @@ func WriteCacheHeader(w *http.ResponseWriter) {
 	//(*w).Header().Set("Expires", time.Now().Add(time.Minute*5).Format(http.TimeFormat))
 }
 
+// ReleaseVersion is the game version a data release stands for, its name or the tag if it has none. Hook, poller and
+// DOFUS_VERSION all name releases this way, so the same release is never updated twice under two versions.
+func ReleaseVersion(name string, tagName string) string {
+	if name != "" {
+		return name
+	}
+	return tagName
+}
+
 type GameVersion struct {
 	Version     string    `json:"version"`
 	Release     string    `json:"release"`